@@ -0,0 +1,138 @@
+// Package autotile alternates the split orientation of containers based on
+// their aspect ratio, so that new windows open in the direction in which the
+// focused window is longer.
+package autotile
+
+import (
+	"fmt"
+	"regexp"
+
+	"go.i3wm.org/i3/v4"
+	"go.i3wm.org/i3/v4/internal/daemon"
+)
+
+// Rule configures autotiling for the workspaces and outputs it matches.
+type Rule struct {
+	// Workspace and Output match the workspace and output name of the focused
+	// window. A nil regular expression matches any name.
+	Workspace *regexp.Regexp
+	Output    *regexp.Regexp
+
+	// Disable turns off autotiling for matching windows.
+	Disable bool
+
+	// MaxDepth limits how deeply containers are nested: windows whose depth
+	// (1 for windows which are direct children of their workspace) is at
+	// least MaxDepth are not split further. 0 means no limit.
+	MaxDepth int
+
+	// Ratio is the aspect ratio (height/width) above which windows are split
+	// vertically. 0 means 1, i.e. windows which are taller than wide are split
+	// vertically.
+	Ratio float64
+}
+
+func (r *Rule) matches(workspace, output string) bool {
+	return (r.Workspace == nil || r.Workspace.MatchString(workspace)) &&
+		(r.Output == nil || r.Output.MatchString(output))
+}
+
+// Tiler decides and applies split orientations.
+type Tiler struct {
+	// Rules are evaluated in order, and the first matching rule applies.
+	// Windows not matching any rule are autotiled with the zero Rule.
+	Rules []Rule
+}
+
+// Split returns the layout (SplitH or SplitV) which should be set on window
+// con, or the empty string if nothing needs to be changed.
+func (t *Tiler) Split(tree i3.Tree, con i3.NodeID) i3.Layout {
+	path := tree.Root.PathTo(con)
+	if len(path) < 2 {
+		return ""
+	}
+	var (
+		n      = path[len(path)-1]
+		parent = path[len(path)-2]
+		ws     *i3.Node
+		output *i3.Node
+		depth  int
+	)
+	for i, p := range path {
+		switch p.Type {
+		case i3.OutputNode:
+			output = p
+		case i3.WorkspaceNode:
+			ws = p
+			depth = len(path) - 1 - i
+		case i3.FloatingCon:
+			return "" // floating windows are not tiled
+		}
+	}
+	if ws == nil || output == nil {
+		return "" // e.g. dock clients
+	}
+	var rule Rule
+	for _, r := range t.Rules {
+		if r.matches(ws.Name, output.Name) {
+			rule = r
+			break
+		}
+	}
+	if rule.Disable || (rule.MaxDepth > 0 && depth >= rule.MaxDepth) {
+		return ""
+	}
+	if n.IsFloating() ||
+		n.FullscreenMode != i3.FullscreenNone ||
+		parent.Layout == i3.Tabbed ||
+		parent.Layout == i3.Stacked {
+		return ""
+	}
+	ratio := rule.Ratio
+	if ratio == 0 {
+		ratio = 1
+	}
+	want := i3.SplitH
+	if float64(n.Rect.Height) > float64(n.Rect.Width)*ratio {
+		want = i3.SplitV
+	}
+	if parent.Layout == want {
+		return ""
+	}
+	return want
+}
+
+// Apply sets the split orientation of window con, if required.
+func (t *Tiler) Apply(tree i3.Tree, con i3.NodeID) error {
+	layout := t.Split(tree, con)
+	if layout == "" {
+		return nil
+	}
+	dir := "horizontal"
+	if layout == i3.SplitV {
+		dir = "vertical"
+	}
+	_, err := i3.RunCommand(fmt.Sprintf("[con_id=%d] split %s", con, dir))
+	return err
+}
+
+// Run applies split orientations whenever a window is focused, until i3
+// exits (in which case Run returns nil) or the subscription fails.
+//
+// Run is supported in i3 ≥ v4.14 (2017-09-04).
+func (t *Tiler) Run() error {
+	return daemon.Run(func(ev i3.Event) error {
+		wev, ok := ev.(*i3.WindowEvent)
+		if !ok || wev.Change != "focus" {
+			return nil
+		}
+		tree, err := i3.GetTree()
+		if err != nil {
+			return err
+		}
+		if err := t.Apply(tree, wev.Container.ID); err != nil && !i3.IsUnsuccessful(err) {
+			return err
+		}
+		return nil
+	}, i3.WindowEventType)
+}
@@ -0,0 +1,103 @@
+package autotile
+
+import (
+	"regexp"
+	"testing"
+
+	"go.i3wm.org/i3/v4"
+)
+
+func TestSplit(t *testing.T) {
+	t.Parallel()
+
+	// An output with workspace “1” containing a wide window (2) and a split
+	// container (3) holding a tall window (4), and a floating window (6).
+	tree := i3.Tree{Root: &i3.Node{
+		ID:   100,
+		Type: i3.Root,
+		Nodes: []*i3.Node{{
+			ID:   101,
+			Name: "HDMI-1",
+			Type: i3.OutputNode,
+			Nodes: []*i3.Node{{
+				ID:   102,
+				Type: i3.Con,
+				Nodes: []*i3.Node{{
+					ID:     1,
+					Name:   "1",
+					Type:   i3.WorkspaceNode,
+					Layout: i3.SplitV,
+					Nodes: []*i3.Node{
+						{ID: 2, Type: i3.Con, Window: 2, Rect: i3.Rect{Width: 1000, Height: 500}},
+						{
+							ID:     3,
+							Type:   i3.Con,
+							Layout: i3.SplitH,
+							Nodes: []*i3.Node{
+								{ID: 4, Type: i3.Con, Window: 4, Rect: i3.Rect{Width: 400, Height: 500}},
+							},
+						},
+					},
+					FloatingNodes: []*i3.Node{{
+						ID:    5,
+						Type:  i3.FloatingCon,
+						Nodes: []*i3.Node{{ID: 6, Type: i3.Con, Window: 6, Rect: i3.Rect{Width: 10, Height: 500}}},
+					}},
+				}},
+			}},
+		}},
+	}}
+
+	for _, tt := range []struct {
+		desc  string
+		rules []Rule
+		con   i3.NodeID
+		want  i3.Layout
+	}{
+		{desc: "wide window in vertical split", con: 2, want: i3.SplitH},
+		{desc: "tall window in horizontal split", con: 4, want: i3.SplitV},
+		{desc: "floating window", con: 6, want: ""},
+		{desc: "not a window", con: 42, want: ""},
+		{
+			desc:  "ratio",
+			rules: []Rule{{Ratio: 1.5}},
+			con:   4,
+			want:  "", // already splith
+		},
+		{
+			desc:  "depth limit",
+			rules: []Rule{{MaxDepth: 2}},
+			con:   4,
+			want:  "",
+		},
+		{
+			desc:  "depth limit not reached",
+			rules: []Rule{{MaxDepth: 2}},
+			con:   2,
+			want:  i3.SplitH,
+		},
+		{
+			desc: "disabled workspace",
+			rules: []Rule{
+				{Workspace: regexp.MustCompile(`^1$`), Disable: true},
+			},
+			con:  2,
+			want: "",
+		},
+		{
+			desc: "first matching rule wins",
+			rules: []Rule{
+				{Output: regexp.MustCompile(`^DP-`), Disable: true},
+				{Output: regexp.MustCompile(`^HDMI-`), MaxDepth: 5},
+				{Disable: true},
+			},
+			con:  4,
+			want: i3.SplitV,
+		},
+	} {
+		tl := &Tiler{Rules: tt.rules}
+		if got := tl.Split(tree, tt.con); got != tt.want {
+			t.Errorf("%s: Split(%d) = %q, want %q", tt.desc, tt.con, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,289 @@
+// Package bar models the workspace buttons of an i3 bar, so that programs can
+// draw their own workspace buttons (e.g. in a custom bar or a terminal) which
+// look and behave like i3bar’s.
+package bar
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.i3wm.org/i3/v4"
+)
+
+// State indicates how a workspace button is drawn.
+type State int
+
+// i3bar distinguishes the following workspace button states:
+const (
+	Inactive State = iota // not visible on any output
+	Active                // visible, but not focused
+	Focused               // visible and focused
+	Urgent                // urgency hint set, takes precedence over all others
+)
+
+// String implements fmt.Stringer.
+func (s State) String() string {
+	switch s {
+	case Inactive:
+		return "inactive"
+	case Active:
+		return "active"
+	case Focused:
+		return "focused"
+	case Urgent:
+		return "urgent"
+	}
+	return "State(" + strconv.Itoa(int(s)) + ")"
+}
+
+// Colors are the colors (in #rrggbb notation) with which a button is drawn.
+type Colors struct {
+	Border     string
+	Background string
+	Text       string
+}
+
+// Button is a single workspace button.
+type Button struct {
+	// Workspace is the workspace which the button represents.
+	Workspace i3.Workspace
+
+	// Label is the text to display, i.e. the workspace name after applying
+	// strip_workspace_numbers or strip_workspace_name.
+	Label string
+
+	State  State
+	Colors Colors
+}
+
+// Output is the ordered list of workspace buttons displayed on an output.
+type Output struct {
+	Name    string
+	Buttons []Button
+}
+
+// Label returns the text which i3bar displays for workspace w.
+func Label(cfg i3.BarConfig, w i3.Workspace) string {
+	if w.Num < 0 {
+		return w.Name
+	}
+	if cfg.StripWorkspaceNumbers {
+		// Like i3bar, skip all leading characters which occur in the
+		// workspace number, followed by an optional colon.
+		num := strconv.FormatInt(w.Num, 10)
+		offset := 0
+		for offset < len(w.Name) && strings.IndexByte(num, w.Name[offset]) != -1 {
+			offset++
+		}
+		if offset > 0 && offset < len(w.Name) && w.Name[offset] == ':' {
+			offset++
+		}
+		if offset >= len(w.Name) {
+			return num // name consists of only the number (and colon)
+		}
+		return w.Name[offset:]
+	}
+	if cfg.StripWorkspaceName {
+		return strconv.FormatInt(w.Num, 10)
+	}
+	return w.Name
+}
+
+// StateOf returns the State in which i3bar draws workspace w.
+func StateOf(w i3.Workspace) State {
+	switch {
+	case w.Urgent:
+		return Urgent
+	case w.Focused:
+		return Focused
+	case w.Visible:
+		return Active
+	}
+	return Inactive
+}
+
+// ColorsOf returns the colors configured for buttons in state s.
+func ColorsOf(c i3.BarConfigColors, s State) Colors {
+	switch s {
+	case Urgent:
+		return Colors{c.UrgentWorkspaceBorder, c.UrgentWorkspaceBackground, c.UrgentWorkspaceText}
+	case Focused:
+		return Colors{c.FocusedWorkspaceBorder, c.FocusedWorkspaceBackground, c.FocusedWorkspaceText}
+	case Active:
+		return Colors{c.ActiveWorkspaceBorder, c.ActiveWorkspaceBackground, c.ActiveWorkspaceText}
+	}
+	return Colors{c.InactiveWorkspaceBorder, c.InactiveWorkspaceBackground, c.InactiveWorkspaceText}
+}
+
+// showsOn returns whether a bar configured by cfg is displayed on output o.
+func showsOn(cfg i3.BarConfig, o i3.Output) bool {
+	if !o.Active {
+		return false
+	}
+	if len(cfg.Outputs) == 0 {
+		return true
+	}
+	for _, name := range cfg.Outputs {
+		if name == o.Name || name == "*" || (name == "primary" && o.Primary) {
+			return true
+		}
+	}
+	return false
+}
+
+// Buttons computes the workspace buttons of a bar configured by cfg. The
+// result contains one entry per output on which the bar is displayed, in the
+// order of outputs. Workspaces retain the order in which i3 returned them.
+func Buttons(cfg i3.BarConfig, outputs []i3.Output, workspaces []i3.Workspace) []Output {
+	var result []Output
+	for _, o := range outputs {
+		if !showsOn(cfg, o) {
+			continue
+		}
+		out := Output{Name: o.Name}
+		if cfg.WorkspaceButtons {
+			for _, w := range workspaces {
+				if w.Output != o.Name {
+					continue
+				}
+				s := StateOf(w)
+				out.Buttons = append(out.Buttons, Button{
+					Workspace: w,
+					Label:     Label(cfg, w),
+					State:     s,
+					Colors:    ColorsOf(cfg.Colors, s),
+				})
+			}
+		}
+		result = append(result, out)
+	}
+	return result
+}
+
+// Model keeps the workspace buttons of a bar up to date.
+//
+// Model is safe for concurrent use.
+type Model struct {
+	mu         sync.Mutex
+	cfg        i3.BarConfig
+	outputs    []i3.Output
+	workspaces []i3.Workspace
+}
+
+// New returns a Model for the bar with the specified barID (see
+// i3.GetBarIDs), initialized with the current bar configuration, outputs and
+// workspaces.
+func New(barID string) (*Model, error) {
+	cfg, err := i3.GetBarConfig(barID)
+	if err != nil {
+		return nil, err
+	}
+	m := &Model{cfg: cfg}
+	if err := m.refresh(true); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (m *Model) refresh(outputs bool) error {
+	var (
+		outs []i3.Output
+		err  error
+	)
+	if outputs {
+		if outs, err = i3.GetOutputs(); err != nil {
+			return err
+		}
+	}
+	ws, err := i3.GetWorkspaces()
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if outputs {
+		m.outputs = outs
+	}
+	m.workspaces = ws
+	return nil
+}
+
+// Config returns the bar configuration the Model currently uses.
+func (m *Model) Config() i3.BarConfig {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.cfg
+}
+
+// Outputs returns the current workspace buttons, see Buttons.
+func (m *Model) Outputs() []Output {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return Buttons(m.cfg, m.outputs, m.workspaces)
+}
+
+// Handle updates the Model in response to ev and returns whether the
+// workspace buttons may have changed. Like i3bar, Handle re-fetches the
+// workspaces (and outputs, for OutputEvents) instead of interpreting the event
+// contents.
+func (m *Model) Handle(ev i3.Event) (bool, error) {
+	switch ev := ev.(type) {
+	case *i3.WorkspaceEvent:
+		return true, m.refresh(false)
+
+	case *i3.OutputEvent:
+		return true, m.refresh(true)
+
+	case *i3.BarconfigUpdateEvent:
+		if ev.ID != m.Config().ID {
+			return false, nil
+		}
+		// The event omits colors which are not explicitly configured, so
+		// re-fetch the configuration to have GetBarConfig fill in defaults.
+		cfg, err := i3.GetBarConfig(ev.ID)
+		if err != nil {
+			return false, err
+		}
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.cfg = cfg
+		return true, nil
+	}
+	return false, nil
+}
+
+// Run subscribes to the events which affect workspace buttons and calls fn
+// with the current workspace buttons, once the subscription is established
+// (and again after i3 restarts) and then whenever they may have changed. Run
+// returns when the subscription fails or fn returns false.
+//
+// Run is supported in i3 ≥ v4.15 (2018-03-10).
+func (m *Model) Run(fn func([]Output) bool) error {
+	// Subscribe to tick events as well: i3 sends a tick event right after
+	// subscribing, from which on no change can be missed.
+	recv := i3.Subscribe(i3.WorkspaceEventType, i3.OutputEventType, i3.BarconfigUpdateEventType, i3.TickEventType)
+	defer recv.Close()
+	for recv.Next() {
+		var changed bool
+		if ev, ok := recv.Event().(*i3.TickEvent); ok {
+			if !ev.First {
+				continue
+			}
+			// Catch up on changes which happened before subscribing.
+			if err := m.refresh(true); err != nil {
+				return err
+			}
+			changed = true
+		} else {
+			var err error
+			if changed, err = m.Handle(recv.Event()); err != nil {
+				return err
+			}
+		}
+		if changed && !fn(m.Outputs()) {
+			return nil
+		}
+	}
+	return recv.Close()
+}
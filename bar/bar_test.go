@@ -0,0 +1,121 @@
+package bar
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.i3wm.org/i3/v4"
+)
+
+func TestLabel(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name         string
+		num          int64
+		stripNumbers bool
+		stripName    bool
+		want         string
+	}{
+		{name: "1: www", num: 1, want: "1: www"},
+		{name: "1:www", num: 1, stripNumbers: true, want: "www"},
+		{name: "12:mail", num: 12, stripNumbers: true, want: "mail"},
+		{name: "3", num: 3, stripNumbers: true, want: "3"},
+		{name: "3:", num: 3, stripNumbers: true, want: "3"},
+		{name: "chat", num: -1, stripNumbers: true, want: "chat"},
+		{name: "2:code", num: 2, stripName: true, want: "2"},
+		{name: "chat", num: -1, stripName: true, want: "chat"},
+	} {
+		cfg := i3.BarConfig{
+			StripWorkspaceNumbers: tt.stripNumbers,
+			StripWorkspaceName:    tt.stripName,
+		}
+		w := i3.Workspace{Num: tt.num, Name: tt.name}
+		if got := Label(cfg, w); got != tt.want {
+			t.Errorf("Label(%+v, %q) = %q, want %q", cfg, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestButtons(t *testing.T) {
+	t.Parallel()
+
+	cfg := i3.BarConfig{
+		WorkspaceButtons: true,
+		Outputs:          []string{"primary", "DP-2"},
+		Colors: i3.BarConfigColors{
+			FocusedWorkspaceText:        "#ffffff",
+			FocusedWorkspaceBackground:  "#285577",
+			FocusedWorkspaceBorder:      "#4c7899",
+			ActiveWorkspaceText:         "#ffffff",
+			ActiveWorkspaceBackground:   "#5f676a",
+			ActiveWorkspaceBorder:       "#333333",
+			InactiveWorkspaceText:       "#888888",
+			InactiveWorkspaceBackground: "#222222",
+			InactiveWorkspaceBorder:     "#333333",
+			UrgentWorkspaceText:         "#ffffff",
+			UrgentWorkspaceBackground:   "#900000",
+			UrgentWorkspaceBorder:       "#2f343a",
+		},
+	}
+	outputs := []i3.Output{
+		{Name: "xroot-0"},
+		{Name: "DP-1", Active: true, Primary: true},
+		{Name: "DP-2", Active: true},
+		{Name: "HDMI-1", Active: true},
+	}
+	workspaces := []i3.Workspace{
+		{Num: 1, Name: "1", Output: "DP-1", Visible: true, Focused: true},
+		{Num: 2, Name: "2", Output: "DP-1", Urgent: true},
+		{Num: 3, Name: "3", Output: "DP-2", Visible: true},
+		{Num: 4, Name: "4", Output: "DP-2"},
+		{Num: 5, Name: "5", Output: "HDMI-1", Visible: true},
+	}
+	got := Buttons(cfg, outputs, workspaces)
+	want := []Output{
+		{
+			Name: "DP-1",
+			Buttons: []Button{
+				{
+					Workspace: workspaces[0],
+					Label:     "1",
+					State:     Focused,
+					Colors:    Colors{"#4c7899", "#285577", "#ffffff"},
+				},
+				{
+					Workspace: workspaces[1],
+					Label:     "2",
+					State:     Urgent,
+					Colors:    Colors{"#2f343a", "#900000", "#ffffff"},
+				},
+			},
+		},
+		{
+			Name: "DP-2",
+			Buttons: []Button{
+				{
+					Workspace: workspaces[2],
+					Label:     "3",
+					State:     Active,
+					Colors:    Colors{"#333333", "#5f676a", "#ffffff"},
+				},
+				{
+					Workspace: workspaces[3],
+					Label:     "4",
+					State:     Inactive,
+					Colors:    Colors{"#333333", "#222222", "#888888"},
+				},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("unexpected Buttons result: (-want +got)\n%s", diff)
+	}
+
+	cfg.WorkspaceButtons = false
+	for _, o := range Buttons(cfg, outputs, workspaces) {
+		if len(o.Buttons) > 0 {
+			t.Errorf("output %s: unexpected buttons with workspace_buttons disabled: %+v", o.Name, o.Buttons)
+		}
+	}
+}
@@ -35,12 +35,24 @@ type BarConfigColors struct {
 	BindingModeBorder     string `json:"binding_mode_border"`
 }
 
+// BarBinding describes a mouse binding configured in a bar block, e.g.
+// “bindsym button4 nop”.
+//
+// BarBinding is supported in i3 ≥ v4.12 (2016-03-06).
+type BarBinding struct {
+	InputCode int64  `json:"input_code"` // mouse button, e.g. 4 for button4
+	Command   string `json:"command"`
+
+	// The Release field was added in i3 v4.14 (2017-09-04).
+	Release bool `json:"release"`
+}
+
 // BarConfig describes a serialized bar configuration block.
 //
 // See https://i3wm.org/docs/ipc.html#_bar_config_reply for more details.
 type BarConfig struct {
 	ID                   string          `json:"id"`
-	Mode                 string          `json:"mode"`
+	Mode                 string          `json:"mode"` // dock, hide or invisible
 	Position             string          `json:"position"`
 	StatusCommand        string          `json:"status_command"`
 	Font                 string          `json:"font"`
@@ -48,6 +60,62 @@ type BarConfig struct {
 	BindingModeIndicator bool            `json:"binding_mode_indicator"`
 	Verbose              bool            `json:"verbose"`
 	Colors               BarConfigColors `json:"colors"`
+
+	// Outputs lists the outputs on which the bar is shown. Empty means all
+	// outputs.
+	Outputs []string `json:"outputs"`
+
+	// I3barCommand is only set if configured, otherwise i3bar is used.
+	I3barCommand string `json:"i3bar_command"`
+
+	// The HiddenState and Modifier fields were added in i3 v4.6 (2013-08-07).
+	HiddenState string `json:"hidden_state"` // hide or show
+	Modifier    int64  `json:"modifier"`     // X11 modifier mask, 0 if none
+
+	// The SeparatorSymbol field was added in i3 v4.7 (2013-12-22).
+	SeparatorSymbol string `json:"separator_symbol"`
+
+	// The StripWorkspaceNumbers field was added in i3 v4.9 (2015-02-28).
+	StripWorkspaceNumbers bool `json:"strip_workspace_numbers"`
+
+	// The TrayPadding field was added in i3 v4.10 (2015-03-29).
+	TrayPadding int64 `json:"tray_padding"`
+
+	// The TrayOutputs and Bindings fields were added in i3 v4.12
+	// (2016-03-06). Older versions sent a single tray_output string, which
+	// UnmarshalJSON stores in TrayOutputs.
+	TrayOutputs []string     `json:"tray_outputs"`
+	Bindings    []BarBinding `json:"bindings"`
+
+	// The StripWorkspaceName field was added in i3 v4.18 (2020-01-28).
+	StripWorkspaceName bool `json:"strip_workspace_name"`
+
+	// The WorkspaceMinWidth field was added in i3 v4.22 (2023-01-02).
+	WorkspaceMinWidth int64 `json:"workspace_min_width"`
+
+	// The Padding field was added in i3 v4.23 (2023-10-29). Its X, Y, Width
+	// and Height fields contain the left, top, right and bottom padding in
+	// pixels, respectively.
+	Padding Rect `json:"padding"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler. In addition to the fields of
+// BarConfig, it understands the tray_output string of i3 < v4.12.
+func (c *BarConfig) UnmarshalJSON(b []byte) error {
+	type barConfig BarConfig // without the UnmarshalJSON method
+	if err := json.Unmarshal(b, (*barConfig)(c)); err != nil {
+		return err
+	}
+	var old struct {
+		TrayOutput *string `json:"tray_output"`
+	}
+	if err := json.Unmarshal(b, &old); err != nil {
+		return err
+	}
+	if c.TrayOutputs == nil && old.TrayOutput != nil {
+		c.TrayOutputs = []string{*old.TrayOutput}
+	}
+	return nil
 }
 
 // GetBarIDs returns an array of configured bar IDs.
@@ -70,9 +138,18 @@ func GetBarIDs() ([]string, error) {
 //
 // GetBarConfig is supported in i3 ≥ v4.1 (2011-11-11).
 func GetBarConfig(barID string) (BarConfig, error) {
+	cfg, _, err := GetBarConfigRaw(barID)
+	return cfg, err
+}
+
+// GetBarConfigRaw is like GetBarConfig, but additionally returns the JSON
+// object as sent by i3, e.g. for fields which BarConfig does not model.
+//
+// GetBarConfigRaw is supported in i3 ≥ v4.1 (2011-11-11).
+func GetBarConfigRaw(barID string) (BarConfig, json.RawMessage, error) {
 	reply, err := roundTrip(messageTypeGetBarConfig, []byte(barID))
 	if err != nil {
-		return BarConfig{}, err
+		return BarConfig{}, nil, err
 	}
 
 	cfg := BarConfig{
@@ -107,5 +184,5 @@ func GetBarConfig(barID string) (BarConfig, error) {
 		},
 	}
 	err = json.Unmarshal(reply.Payload, &cfg)
-	return cfg, err
+	return cfg, reply.Payload, err
 }
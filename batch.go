@@ -0,0 +1,244 @@
+package i3
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync/atomic"
+)
+
+// Batch pipelines requests: Do sends all requests to i3 at once over a single
+// connection, and then receives the replies in order. Compared to sending the
+// requests one after the other, this saves a round trip per request.
+//
+// The request methods store the pointer to the result, which Do fills in,
+// like json.Unmarshal does:
+//
+//	var (
+//		b  i3.Batch
+//		ws []i3.Workspace
+//		t  i3.Tree
+//	)
+//	b.GetWorkspaces(&ws)
+//	b.GetTree(&t)
+//	if err := b.Do(); err != nil {
+//		return err
+//	}
+//
+// Batch is not safe for concurrent use.
+type Batch struct {
+	reqs []batchRequest
+}
+
+type batchRequest struct {
+	t       messageType
+	payload []byte
+	reply   func(r io.Reader) error
+
+	// Set by socket.batch for IPCHooks.
+	replySize int
+	err       error
+}
+
+func (b *Batch) add(t messageType, payload []byte, v interface{}) {
+	b.reqs = append(b.reqs, batchRequest{
+		t:       t,
+		payload: payload,
+		reply: func(r io.Reader) error {
+			return json.NewDecoder(r).Decode(v)
+		},
+	})
+}
+
+// RunCommand adds a RUN_COMMAND request (see RunCommand) and stores the
+// results in *results, which may be nil. If a command is unsuccessful, Do
+// returns a *CommandUnsuccessfulError.
+func (b *Batch) RunCommand(command string, results *[]CommandResult) {
+	if results == nil {
+		results = new([]CommandResult)
+	}
+	b.reqs = append(b.reqs, batchRequest{
+		t:       messageTypeRunCommand,
+		payload: []byte(command),
+		reply: func(r io.Reader) error {
+			if err := json.NewDecoder(r).Decode(results); err != nil {
+				return err
+			}
+			for _, cr := range *results {
+				if !cr.Success {
+					return &CommandUnsuccessfulError{
+						command: command,
+						cr:      cr,
+					}
+				}
+			}
+			return nil
+		},
+	})
+}
+
+// GetWorkspaces adds a GET_WORKSPACES request, see GetWorkspaces.
+func (b *Batch) GetWorkspaces(workspaces *[]Workspace) {
+	b.add(messageTypeGetWorkspaces, nil, workspaces)
+}
+
+// GetOutputs adds a GET_OUTPUTS request, see GetOutputs.
+func (b *Batch) GetOutputs(outputs *[]Output) {
+	b.add(messageTypeGetOutputs, nil, outputs)
+}
+
+// GetTree adds a GET_TREE request, see GetTree.
+func (b *Batch) GetTree(tree *Tree) {
+	b.reqs = append(b.reqs, batchRequest{
+		t: messageTypeGetTree,
+		reply: func(r io.Reader) error {
+			root := &Node{}
+			if err := json.NewDecoder(r).Decode(root); err != nil {
+				return err
+			}
+			tree.Root = root
+			return nil
+		},
+	})
+}
+
+// GetMarks adds a GET_MARKS request, see GetMarks.
+func (b *Batch) GetMarks(marks *[]string) {
+	b.add(messageTypeGetMarks, nil, marks)
+}
+
+// GetBindingModes adds a GET_BINDING_MODES request, see GetBindingModes.
+func (b *Batch) GetBindingModes(modes *[]string) {
+	b.add(messageTypeGetBindingModes, nil, modes)
+}
+
+// GetVersion adds a GET_VERSION request, see GetVersion.
+func (b *Batch) GetVersion(version *Version) {
+	b.add(messageTypeGetVersion, nil, version)
+}
+
+// Do sends all requests which were added since the last call to Do and
+// stores their replies. Do returns the first error, but stores all replies
+// regardless, e.g. all command results even if a command was unsuccessful.
+//
+// Like other requests, Do reconnects and sends the requests again if the
+// connection to i3 fails, unless they include RUN_COMMAND requests and i3
+// might have received them already: commands must not run twice.
+func (b *Batch) Do() error {
+	reqs := b.reqs
+	b.reqs = nil
+	for _, r := range reqs {
+		if err := checkMessageType(r.t); err != nil {
+			return err
+		}
+	}
+	rts := make([]*RoundTrip, len(reqs))
+	for i, r := range reqs {
+		rts[i] = startRoundTrip(r.t, r.payload)
+	}
+	var firstErr error
+	_, err := withConn(func(s *socket) (message, error) {
+		var err error
+		firstErr, err = s.batch(reqs)
+		return message{}, err
+	})
+	for i, r := range reqs {
+		if err != nil {
+			r.err = err
+		}
+		endRoundTrip(rts[i], r.replySize, r.err)
+	}
+	if err != nil {
+		return err
+	}
+	return firstErr
+}
+
+// unretriableError wraps a connection error after which the requests must not
+// be sent again, see withConn.
+type unretriableError struct {
+	err error
+}
+
+func (e *unretriableError) Error() string { return e.err.Error() }
+
+func (e *unretriableError) Unwrap() error { return e.err }
+
+// countingWriter counts the bytes written to w.
+type countingWriter struct {
+	w io.Writer
+	n atomic.Int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+// batch sends all requests and then receives their replies. The first error
+// of the reply functions is returned as replyErr, whereas err is an error of
+// the connection. batch sets the reply size and error of each request.
+func (s *socket) batch(reqs []batchRequest) (replyErr, err error) {
+	if s == nil {
+		return nil, ErrNotConnected
+	}
+	for i := range reqs {
+		reqs[i].replySize, reqs[i].err = 0, nil
+	}
+	w := &countingWriter{w: s.conn}
+	commands := slices.ContainsFunc(reqs, func(r batchRequest) bool {
+		return r.t == messageTypeRunCommand
+	})
+	connErr := func(err error) error {
+		var perr *ProtocolError
+		if commands && w.n.Load() > 0 && !errors.As(err, &perr) {
+			// i3 might have run the commands already.
+			return &unretriableError{err: err}
+		}
+		return err
+	}
+	// Send the requests concurrently with receiving replies: i3 might stop
+	// reading requests while its replies are not read.
+	sent := make(chan error, 1)
+	go func() {
+		sender := &socket{
+			conn: struct {
+				io.Reader
+				io.Writer
+			}{s.conn, w},
+			order: s.order,
+		}
+		for _, r := range reqs {
+			if err := sender.sendMsg(r.t, r.payload); err != nil {
+				sent <- err
+				return
+			}
+		}
+		sent <- nil
+	}()
+	for i := range reqs {
+		r := &reqs[i]
+		h, err := s.recvHeader()
+		if err != nil {
+			return nil, connErr(err)
+		}
+		if h.Type != r.t {
+			return nil, &ProtocolError{Reason: fmt.Sprintf("unexpected reply type %d, expected %d", h.Type, r.t)}
+		}
+		r.replySize = int(h.Length)
+		lr := &io.LimitedReader{R: s.conn, N: int64(h.Length)}
+		if r.err = r.reply(lr); r.err != nil && replyErr == nil {
+			replyErr = r.err
+		}
+		if _, err := io.Copy(io.Discard, lr); err != nil {
+			return nil, connErr(err)
+		}
+	}
+	if err := <-sent; err != nil {
+		return nil, connErr(err)
+	}
+	return replyErr, nil
+}
@@ -0,0 +1,156 @@
+package i3
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.i3wm.org/i3/v4/internal/i3test"
+)
+
+func TestBatch(t *testing.T) {
+	t.Parallel()
+
+	order := binary.LittleEndian
+	client, server := net.Pipe()
+	defer client.Close()
+	replies := map[messageType]string{
+		messageTypeRunCommand:    `[{"success": true}, {"success": false, "error": "no such mark"}]`,
+		messageTypeGetWorkspaces: `[{"num": 1, "name": "1", "focused": true}]`,
+		messageTypeGetTree:       `{"id": 1, "type": "root", "nodes": [{"id": 2, "type": "output", "nodes": []}]}`,
+		messageTypeGetMarks:      `["a", "b"]`,
+	}
+	var requests []messageType
+	done := make(chan error, 1)
+	go func() {
+		defer server.Close()
+		sock := &socket{conn: server, order: order}
+		for range replies {
+			msg, err := sock.recvMsg()
+			if err != nil {
+				done <- err
+				return
+			}
+			requests = append(requests, msg.Type)
+			if _, err := server.Write(msgBytes(order, msg.Type, replies[msg.Type])); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+
+	var (
+		b       Batch
+		results []CommandResult
+		ws      []Workspace
+		tree    Tree
+		marks   []string
+	)
+	b.RunCommand("focus left; [con_mark=x] focus", &results)
+	b.GetWorkspaces(&ws)
+	b.GetTree(&tree)
+	b.GetMarks(&marks)
+	replyErr, err := (&socket{conn: client, order: order}).batch(b.reqs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if !IsUnsuccessful(replyErr) {
+		t.Errorf("batch: got error %v, want CommandUnsuccessfulError", replyErr)
+	}
+
+	for i, r := range b.reqs {
+		if got, want := r.replySize, len(replies[r.t]); got != want {
+			t.Errorf("request %d: replySize = %d, want %d", i, got, want)
+		}
+		if got, want := r.err != nil, r.t == messageTypeRunCommand; got != want {
+			t.Errorf("request %d: err = %v, want error: %v", i, r.err, want)
+		}
+	}
+
+	want := []messageType{messageTypeRunCommand, messageTypeGetWorkspaces, messageTypeGetTree, messageTypeGetMarks}
+	if diff := cmp.Diff(want, requests); diff != "" {
+		t.Errorf("unexpected requests: (-want +got)\n%s", diff)
+	}
+	if got, want := len(results), 2; got != want {
+		t.Errorf("unexpected number of command results: got %d, want %d", got, want)
+	}
+	if diff := cmp.Diff([]Workspace{{Num: 1, Name: "1", Focused: true}}, ws); diff != "" {
+		t.Errorf("unexpected workspaces: (-want +got)\n%s", diff)
+	}
+	if got := nodeIDs(tree.Root.Nodes); !cmp.Equal(got, []NodeID{2}) {
+		t.Errorf("unexpected tree: got children %v", got)
+	}
+	if diff := cmp.Diff([]string{"a", "b"}, marks); diff != "" {
+		t.Errorf("unexpected marks: (-want +got)\n%s", diff)
+	}
+}
+
+func TestBatchConnectionLost(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name      string
+		command   bool
+		wantRetry bool
+	}{
+		{name: "Queries", command: false, wantRetry: true},
+		{name: "Command", command: true, wantRetry: false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			client, server := net.Pipe()
+			defer client.Close()
+			go func() {
+				// Read the first request, then lose the connection.
+				defer server.Close()
+				(&socket{conn: server, order: binary.LittleEndian}).recvMsg()
+			}()
+			var (
+				b     Batch
+				marks []string
+			)
+			if tt.command {
+				b.RunCommand("kill", nil)
+			}
+			b.GetMarks(&marks)
+			_, err := (&socket{conn: client, order: binary.LittleEndian}).batch(b.reqs)
+			if err == nil {
+				t.Fatal("batch unexpectedly succeeded")
+			}
+			var uerr *unretriableError
+			if got := !errors.As(err, &uerr); got != tt.wantRetry {
+				t.Errorf("batch: got error %#v, want retry: %v", err, tt.wantRetry)
+			}
+		})
+	}
+}
+
+func TestBatchNoRetry(t *testing.T) {
+	useFakeI3(t, func(typ uint32, payload []byte) []byte {
+		if typ == i3test.GetVersion {
+			return []byte(i3test.Version)
+		}
+		return nil
+	})
+
+	var calls int
+	lost := errors.New("connection lost")
+	_, err := withConn(func(s *socket) (message, error) {
+		calls++
+		if s == nil {
+			return message{}, ErrNotConnected // not connected yet
+		}
+		return message{}, &unretriableError{err: lost}
+	})
+	if !errors.Is(err, ErrNotConnected) || !errors.Is(err, lost) {
+		t.Errorf("withConn: got error %v, want ErrNotConnected wrapping %v", err, lost)
+	}
+	if got, want := calls, 2; got != want {
+		t.Errorf("withConn: fn called %d times, want %d", got, want)
+	}
+}
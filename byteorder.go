@@ -1,7 +1,9 @@
 package i3
 
 import (
+	"bytes"
 	"encoding/binary"
+	"fmt"
 	"io"
 	"strings"
 )
@@ -44,15 +46,17 @@ func detectByteOrder(conn io.ReadWriter) (binary.ByteOrder, error) {
 	if _, err := io.ReadFull(conn, header[:]); err != nil {
 		return nil, err
 	}
+	if !bytes.Equal(header[:6], magic[:]) {
+		return nil, &ProtocolError{Reason: fmt.Sprintf("invalid magic string %q", header[:6])}
+	}
 	if messageType(binary.BigEndian.Uint32(header[10:14])) == messageReplyTypeCommand {
 		order := binary.LittleEndian // our big endian message was not answered
 		// Read remaining payload
-		_, err := io.ReadFull(conn, make([]byte, order.Uint32(header[6:10])))
-		return order, err
+		return order, discard(conn, order.Uint32(header[6:10]))
 	}
 	order := binary.BigEndian // our big endian message was answered
 	// Read remaining payload
-	if _, err := io.ReadFull(conn, make([]byte, order.Uint32(header[6:10]))); err != nil {
+	if err := discard(conn, order.Uint32(header[6:10])); err != nil {
 		return order, err
 	}
 
@@ -61,3 +65,12 @@ func detectByteOrder(conn io.ReadWriter) (binary.ByteOrder, error) {
 	_, err := sock.recvMsg()
 	return binary.BigEndian, err
 }
+
+// discard reads and discards a payload of the specified length.
+func discard(r io.Reader, length uint32) error {
+	if length > MaxMessageSize {
+		return &ProtocolError{Reason: fmt.Sprintf("message size %d exceeds MaxMessageSize (%d)", length, MaxMessageSize)}
+	}
+	_, err := io.CopyN(io.Discard, r, int64(length))
+	return err
+}
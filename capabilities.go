@@ -0,0 +1,286 @@
+package i3
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CapabilitySet describes which features the running i3 (or i3-compatible
+// window manager) supports.
+type CapabilitySet struct {
+	Version Version
+
+	// Probed is true if the capabilities were determined by probing, which
+	// is done for variants other than stock i3 and sway.
+	Probed bool
+
+	// Messages contains the supported message types by their i3-msg(1)
+	// names, e.g. "get_tree".
+	Messages map[string]bool
+
+	// Events contains the supported event types.
+	Events map[EventType]bool
+
+	// Fields contains the supported reply fields which were added after the
+	// corresponding message type, by type and field name, e.g.
+	// "BarConfig.Padding".
+	Fields map[string]bool
+}
+
+// messageNames contains the i3-msg(1) names of the message types.
+var messageNames = map[messageType]string{
+	messageTypeRunCommand:      "run_command",
+	messageTypeGetWorkspaces:   "get_workspaces",
+	messageTypeSubscribe:       "subscribe",
+	messageTypeGetOutputs:      "get_outputs",
+	messageTypeGetTree:         "get_tree",
+	messageTypeGetMarks:        "get_marks",
+	messageTypeGetBarConfig:    "get_bar_config",
+	messageTypeGetVersion:      "get_version",
+	messageTypeGetBindingModes: "get_binding_modes",
+	messageTypeGetConfig:       "get_config",
+	messageTypeSendTick:        "send_tick",
+	messageTypeSync:            "sync",
+	messageTypeGetBindingState: "get_binding_state",
+}
+
+// fieldAtLeast lists the i3 versions which added reply fields, see the field
+// documentation.
+var fieldAtLeast = map[string]majorMinor{
+	"BarConfig.HiddenState":           {4, 6},
+	"BarConfig.Modifier":              {4, 6},
+	"BarConfig.SeparatorSymbol":       {4, 7},
+	"BarConfig.StripWorkspaceNumbers": {4, 9},
+	"BarConfig.TrayPadding":           {4, 10},
+	"BarConfig.TrayOutputs":           {4, 12},
+	"BarConfig.Bindings":              {4, 12},
+	"BarBinding.Release":              {4, 14},
+	"BarConfig.StripWorkspaceName":    {4, 18},
+	"Config.IncludedConfigs":          {4, 20},
+	"BarConfig.WorkspaceMinWidth":     {4, 22},
+	"BarConfig.Padding":               {4, 23},
+}
+
+// The sway tables list the sway versions which support i3 features. Missing
+// entries are not supported by sway, e.g. sway replies to sync requests with
+// success=false.
+var (
+	swayMessageAtLeast = map[messageType]majorMinor{
+		messageTypeRunCommand:      {1, 0},
+		messageTypeGetWorkspaces:   {1, 0},
+		messageTypeSubscribe:       {1, 0},
+		messageTypeGetOutputs:      {1, 0},
+		messageTypeGetTree:         {1, 0},
+		messageTypeGetMarks:        {1, 0},
+		messageTypeGetBarConfig:    {1, 0},
+		messageTypeGetVersion:      {1, 0},
+		messageTypeGetBindingModes: {1, 0},
+		messageTypeGetConfig:       {1, 0},
+		messageTypeSendTick:        {1, 0},
+		messageTypeGetBindingState: {1, 5},
+	}
+
+	swayEventAtLeast = map[EventType]majorMinor{
+		WorkspaceEventType:       {1, 0},
+		ModeEventType:            {1, 0},
+		WindowEventType:          {1, 0},
+		BarconfigUpdateEventType: {1, 0},
+		BindingEventType:         {1, 0},
+		ShutdownEventType:        {1, 0},
+		TickEventType:            {1, 0},
+		BarStateUpdateEventType:  {1, 0},
+		OutputEventType:          {1, 8},
+	}
+
+	swayFieldAtLeast = map[string]majorMinor{
+		"BarConfig.HiddenState":           {1, 0},
+		"BarConfig.Modifier":              {1, 0},
+		"BarConfig.SeparatorSymbol":       {1, 0},
+		"BarConfig.StripWorkspaceNumbers": {1, 0},
+		"BarConfig.TrayPadding":           {1, 0},
+		"BarConfig.TrayOutputs":           {1, 0},
+		"BarConfig.Bindings":              {1, 0},
+		"BarBinding.Release":              {1, 0},
+		"BarConfig.StripWorkspaceName":    {1, 0},
+		"BarConfig.WorkspaceMinWidth":     {1, 6},
+	}
+)
+
+func (v Version) atLeast(mm majorMinor) bool {
+	return v.Major > mm.major || (v.Major == mm.major && v.Minor >= mm.minor)
+}
+
+// capabilitiesFromTables returns the capabilities of stock i3 and sway
+// versions, and false for other variants.
+func capabilitiesFromTables(v Version) (*CapabilitySet, bool) {
+	messages, events, fields := messageAtLeast, eventAtLeast, fieldAtLeast
+	switch v.Variant {
+	case "":
+	case "sway":
+		messages, events, fields = swayMessageAtLeast, swayEventAtLeast, swayFieldAtLeast
+	default:
+		return nil, false
+	}
+	c := newCapabilitySet(v)
+	for t, name := range messageNames {
+		mm, ok := messages[t]
+		c.Messages[name] = ok && v.atLeast(mm)
+	}
+	for t := range eventAtLeast {
+		mm, ok := events[t]
+		c.Events[t] = ok && v.atLeast(mm)
+	}
+	for f := range fieldAtLeast {
+		mm, ok := fields[f]
+		c.Fields[f] = ok && v.atLeast(mm)
+	}
+	return c, true
+}
+
+func newCapabilitySet(v Version) *CapabilitySet {
+	return &CapabilitySet{
+		Version:  v,
+		Messages: make(map[string]bool),
+		Events:   make(map[EventType]bool),
+		Fields:   make(map[string]bool),
+	}
+}
+
+// probeTimeout is how long probing waits for a reply: i3 does not reply to
+// message types it does not know.
+const probeTimeout = time.Second
+
+// probe sends a message with the specified payload on a dedicated connection
+// and returns the reply payload, or nil if the message type is not supported.
+func probe(t messageType, payload []byte) []byte {
+	sock, conn, err := getIPCSocket(false)
+	if err != nil {
+		return nil
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(probeTimeout))
+	reply, err := sock.roundTrip(t, payload)
+	if err != nil {
+		return nil
+	}
+	var errReply struct {
+		Success *bool `json:"success"`
+		Error   string
+	}
+	if json.Unmarshal(reply.Payload, &errReply) == nil &&
+		errReply.Success != nil && !*errReply.Success && errReply.Error != "" {
+		return nil // e.g. “unknown message type”
+	}
+	return reply.Payload
+}
+
+// probeFields sets the fields of struct type typ which the JSON object in
+// payload contains.
+func (c *CapabilitySet) probeFields(typ reflect.Type, payload []byte) {
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &keys); err != nil {
+		return
+	}
+	for f := range fieldAtLeast {
+		typeName, fieldName, _ := strings.Cut(f, ".")
+		if typeName != typ.Name() {
+			continue
+		}
+		sf, ok := typ.FieldByName(fieldName)
+		if !ok {
+			continue
+		}
+		tag, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		_, c.Fields[f] = keys[tag]
+	}
+}
+
+// probeCapabilities determines the capabilities of an unknown variant by
+// sending messages and subscribing to events.
+func probeCapabilities(v Version) *CapabilitySet {
+	c := newCapabilitySet(v)
+	c.Probed = true
+	for t, name := range messageNames {
+		switch t {
+		case messageTypeRunCommand, messageTypeGetVersion:
+			c.Messages[name] = true // required for probing
+		case messageTypeSubscribe:
+			c.Messages[name] = probe(t, []byte("[]")) != nil
+		case messageTypeSync:
+			// Probing would require an X11 window.
+		case messageTypeSendTick:
+			// Probing would send a tick event to all subscribers.
+		default:
+			c.Messages[name] = probe(t, nil) != nil
+		}
+	}
+	for t := range eventAtLeast {
+		payload, _ := json.Marshal([]EventType{t})
+		var reply struct {
+			Success bool `json:"success"`
+		}
+		b := probe(messageTypeSubscribe, payload)
+		c.Events[t] = b != nil && json.Unmarshal(b, &reply) == nil && reply.Success
+	}
+	if b := probe(messageTypeGetBarConfig, nil); b != nil {
+		var ids []string
+		if json.Unmarshal(b, &ids) == nil && len(ids) > 0 {
+			if b := probe(messageTypeGetBarConfig, []byte(ids[0])); b != nil {
+				c.probeFields(reflect.TypeOf(BarConfig{}), b)
+				var cfg struct {
+					Bindings []json.RawMessage `json:"bindings"`
+				}
+				if json.Unmarshal(b, &cfg) == nil && len(cfg.Bindings) > 0 {
+					c.probeFields(reflect.TypeOf(BarBinding{}), cfg.Bindings[0])
+				}
+			}
+		}
+	}
+	if b := probe(messageTypeGetConfig, nil); b != nil {
+		c.probeFields(reflect.TypeOf(Config{}), b)
+	}
+	return c
+}
+
+// capabilities caches the CapabilitySet per i3 socket.
+var capabilities struct {
+	sync.Mutex
+	path string
+	set  *CapabilitySet
+}
+
+// Capabilities returns which message types, event types and reply fields the
+// running i3 supports. For stock i3 and sway, the capabilities are determined
+// from the version, whereas other variants are probed, which might take a
+// few seconds. The result is cached until the socket path or the version
+// changes, see VersionChangedHook.
+//
+// Note that unlike Capabilities, AtLeast (and hence the message functions)
+// ignores the version of variants.
+//
+// Capabilities is supported in i3 ≥ v4.3 (2012-09-19).
+func Capabilities() (*CapabilitySet, error) {
+	v, err := GetVersion()
+	if err != nil {
+		return nil, err
+	}
+	remote.mu.Lock()
+	path := remote.path
+	remote.mu.Unlock()
+
+	capabilities.Lock()
+	defer capabilities.Unlock()
+	if capabilities.set != nil && capabilities.path == path && capabilities.set.Version == v {
+		return capabilities.set, nil
+	}
+	c, ok := capabilitiesFromTables(v)
+	if !ok {
+		c = probeCapabilities(v)
+	}
+	capabilities.path = path
+	capabilities.set = c
+	return c, nil
+}
@@ -0,0 +1,105 @@
+package i3
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCapabilitiesFromTables(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name    string
+		version Version
+		want    map[string]bool // messages, events (prefixed with "event:") and fields
+	}{
+		{
+			name:    "i3-4.14",
+			version: Version{Major: 4, Minor: 14},
+			want: map[string]bool{
+				"get_config":                   true,
+				"send_tick":                    false,
+				"event:shutdown":               true,
+				"event:tick":                   false,
+				"BarBinding.Release":           true,
+				"BarConfig.StripWorkspaceName": false,
+			},
+		},
+
+		{
+			name:    "i3-4.24",
+			version: Version{Major: 4, Minor: 24},
+			want: map[string]bool{
+				"sync":                   true,
+				"get_binding_state":      true,
+				"event:bar_state_update": true,
+				"BarConfig.Padding":      true,
+			},
+		},
+
+		{
+			name:    "sway-1.5",
+			version: Version{Major: 1, Minor: 5, Variant: "sway"},
+			want: map[string]bool{
+				"send_tick":                   true,
+				"sync":                        false,
+				"get_binding_state":           true,
+				"event:tick":                  true,
+				"event:output":                false,
+				"BarConfig.WorkspaceMinWidth": false,
+				"BarConfig.Padding":           false,
+			},
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			c, ok := capabilitiesFromTables(tt.version)
+			if !ok {
+				t.Fatalf("capabilitiesFromTables(%+v) unexpectedly returned false", tt.version)
+			}
+			for key, want := range tt.want {
+				got := c.Messages[key] || c.Fields[key]
+				if ev, ok := strings.CutPrefix(key, "event:"); ok {
+					got = c.Events[EventType(ev)]
+				}
+				if got != want {
+					t.Errorf("%s: got %v, want %v", key, got, want)
+				}
+			}
+		})
+	}
+
+	t.Run("Unknown", func(t *testing.T) {
+		if _, ok := capabilitiesFromTables(Version{Major: 0, Minor: 1, Variant: "unknown"}); ok {
+			t.Errorf("capabilitiesFromTables unexpectedly returned true for unknown variant")
+		}
+	})
+}
+
+func TestCapabilitiesComplete(t *testing.T) {
+	t.Parallel()
+
+	c, _ := capabilitiesFromTables(Version{Major: 4, Minor: 24})
+	if got, want := len(c.Messages), len(messageAtLeast); got != want {
+		t.Errorf("len(Messages) = %d, want %d (messageNames incomplete?)", got, want)
+	}
+	for f := range fieldAtLeast {
+		typeName, fieldName, _ := strings.Cut(f, ".")
+		var typ reflect.Type
+		switch typeName {
+		case "BarConfig":
+			typ = reflect.TypeOf(BarConfig{})
+		case "BarBinding":
+			typ = reflect.TypeOf(BarBinding{})
+		case "Config":
+			typ = reflect.TypeOf(Config{})
+		default:
+			t.Errorf("%s: unknown type %q", f, typeName)
+			continue
+		}
+		if _, ok := typ.FieldByName(fieldName); !ok {
+			t.Errorf("%s: no such field", f)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+// Binary i3autotile splits the focused window vertically if it is taller
+// than wide, and horizontally otherwise, so that windows open in the
+// direction in which there is more space.
+package main
+
+import (
+	"flag"
+	"log"
+	"regexp"
+
+	"go.i3wm.org/i3/v4/autotile"
+)
+
+var (
+	workspaces = flag.String("workspaces", "", "if non-empty, only autotile workspaces whose name matches this regular expression")
+	outputs    = flag.String("outputs", "", "if non-empty, only autotile outputs whose name matches this regular expression")
+	limit      = flag.Int("limit", 0, "do not split windows which are nested at least this deeply (0: no limit)")
+	ratio      = flag.Float64("ratio", 1, "split vertically if height/width exceeds this ratio")
+)
+
+func main() {
+	flag.Parse()
+	rule := autotile.Rule{
+		MaxDepth: *limit,
+		Ratio:    *ratio,
+	}
+	var err error
+	if *workspaces != "" {
+		if rule.Workspace, err = regexp.Compile(*workspaces); err != nil {
+			log.Fatalf("-workspaces: %v", err)
+		}
+	}
+	if *outputs != "" {
+		if rule.Output, err = regexp.Compile(*outputs); err != nil {
+			log.Fatalf("-outputs: %v", err)
+		}
+	}
+	tiler := &autotile.Tiler{
+		Rules: []autotile.Rule{
+			rule,
+			{Disable: true}, // only reached if rule does not match
+		},
+	}
+	if err := tiler.Run(); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,371 @@
+// Binary i3ipc sends messages to i3 and prints the replies. It accepts the
+// same flags as i3-msg(1) and adds a few extras, e.g. Go template output and
+// a tree view of the layout tree.
+//
+// Exit status is 0 on success, 1 on errors and 2 if i3 reported that (one of)
+// the commands was unsuccessful.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"go.i3wm.org/i3/v4"
+)
+
+const usage = `usage: i3ipc [-s <socket>] [-t <type>] [-q] [-r] [-m] [-format <template>] [-tree|-dot] [message]
+       i3ipc [-s <socket>] query [-o json|table] [-format <template>] <expression>
+
+Sends message (default type: command) to i3 and prints the reply.
+
+Message types:
+  command, run_command   run the i3 command(s) given as message
+  get_workspaces         print workspaces
+  get_outputs            print outputs
+  get_tree               print the layout tree
+  get_marks              print all marks
+  get_bar_config         print bar IDs, or the configuration of bar message
+  get_binding_modes      print all binding modes
+  get_binding_state      print the active binding mode
+  get_version            print the i3 version
+  get_config             print the loaded configuration
+  send_tick              send a tick event with payload message
+  sync                   send a sync request, message is a JSON SyncRequest
+  subscribe              wait for an event of the JSON array of types in message
+  <number>               send a message of this numeric type, e.g. for new i3 features
+
+Flags:
+`
+
+// options contains the values of the command line flags.
+type options struct {
+	msgType string
+	socket  string
+	quiet   bool
+	raw     bool
+	monitor bool
+	format  string
+	tree    bool
+	dot     bool
+	ascii   bool
+	noDock  bool
+	noI3    bool
+}
+
+func (o *options) register(fset *flag.FlagSet) {
+	fset.StringVar(&o.msgType, "t", "command", "message type, see above")
+	fset.StringVar(&o.socket, "s", "", "path to i3’s IPC socket (default: $I3SOCK, then i3 --get-socketpath)")
+	fset.BoolVar(&o.quiet, "q", false, "only print errors, not replies")
+	fset.BoolVar(&o.raw, "r", false, "print compact instead of indented JSON")
+	fset.BoolVar(&o.monitor, "m", false, "with -t subscribe: print events until interrupted instead of exiting after the first event")
+	fset.StringVar(&o.format, "format", "", "execute this text/template on the typed reply (or on each event) instead of printing JSON")
+	fset.BoolVar(&o.tree, "tree", false, "with -t get_tree: print a tree diagram instead of JSON")
+	fset.BoolVar(&o.dot, "dot", false, "with -t get_tree: print a Graphviz DOT digraph instead of JSON")
+	fset.BoolVar(&o.ascii, "ascii", false, "with -tree: use ASCII instead of Unicode box-drawing characters")
+	fset.BoolVar(&o.noDock, "collapse-dock", false, "with -tree or -dot: omit dock clients")
+	fset.BoolVar(&o.noI3, "hide-scratch", false, "with -tree or -dot: omit the __i3 output containing the scratchpad")
+}
+
+// messageTypes maps message type names to their numbers, see
+// https://i3wm.org/docs/ipc.html#_sending_messages_to_i3
+var messageTypes = map[string]uint32{
+	"command":           0,
+	"run_command":       0,
+	"get_workspaces":    1,
+	"get_outputs":       3,
+	"get_tree":          4,
+	"get_marks":         5,
+	"get_bar_config":    6,
+	"get_version":       7,
+	"get_binding_modes": 8,
+	"get_config":        9,
+	"send_tick":         10,
+	"sync":              11,
+	"get_binding_state": 12,
+}
+
+// exitError carries the exit status with which main terminates.
+type exitError struct {
+	status int
+	err    error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+
+func main() {
+	err := run(os.Stdout, os.Args[1:])
+	if err == flag.ErrHelp {
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "i3ipc: %v\n", err)
+		if ee, ok := err.(*exitError); ok {
+			os.Exit(ee.status)
+		}
+		os.Exit(1)
+	}
+}
+
+// run parses the command line flags in args and runs the message or query.
+func run(w io.Writer, args []string) error {
+	fset := flag.NewFlagSet("i3ipc", flag.ContinueOnError)
+	fset.Usage = func() {
+		fmt.Fprint(fset.Output(), usage)
+		fset.PrintDefaults()
+	}
+	var o options
+	o.register(fset)
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+	if path := o.socketPath(); path != "" {
+		i3.SocketPathHook = func() (string, error) { return path, nil }
+	}
+	if fset.NArg() > 0 && fset.Arg(0) == "query" {
+		return runQuery(w, &o, fset.Args()[1:])
+	}
+	return send(w, &o, fset.Args())
+}
+
+// send sends a message of type o.msgType and prints the reply.
+func send(w io.Writer, o *options, args []string) error {
+	var tmpl *template.Template
+	if o.format != "" {
+		var err error
+		if tmpl, err = parseTemplate(o.format); err != nil {
+			return err
+		}
+	}
+	p := &printer{w: w, tmpl: tmpl, quiet: o.quiet, raw: o.raw}
+
+	payload := strings.Join(args, " ")
+	if tmpl == nil && o.msgType != "subscribe" && !(o.msgType == "get_tree" && (o.tree || o.dot)) {
+		// Print i3’s reply as is instead of re-encoding the typed reply,
+		// which would drop fields this package does not model.
+		t, ok := messageTypes[o.msgType]
+		if !ok {
+			n, err := strconv.ParseUint(o.msgType, 0, 32)
+			if err != nil {
+				return fmt.Errorf("unknown message type %q, see -help", o.msgType)
+			}
+			t = uint32(n)
+		}
+		return sendRaw(p, t, payload)
+	}
+	switch o.msgType {
+	case "command", "run_command":
+		crs, err := i3.RunCommand(payload)
+		if err != nil && !i3.IsUnsuccessful(err) {
+			return err
+		}
+		if perr := p.print(crs); perr != nil {
+			return perr
+		}
+		if err != nil {
+			return &exitError{status: 2, err: err}
+		}
+		return nil
+
+	case "get_workspaces":
+		return p.reply(i3.GetWorkspaces())
+
+	case "get_outputs":
+		return p.reply(i3.GetOutputs())
+
+	case "get_tree":
+		t, err := i3.GetTree()
+		if err != nil {
+			return err
+		}
+		if o.tree || o.dot {
+			if o.quiet {
+				return nil
+			}
+			opts := &i3.RenderOptions{
+				CollapseDockareas: o.noDock,
+				HideScratch:       o.noI3,
+				ASCII:             o.ascii,
+			}
+			if o.dot {
+				return t.Root.RenderDOT(w, opts)
+			}
+			return t.Root.Render(w, opts)
+		}
+		return p.print(t.Root)
+
+	case "get_marks":
+		return p.reply(i3.GetMarks())
+
+	case "get_bar_config":
+		if payload == "" {
+			return p.reply(i3.GetBarIDs())
+		}
+		return p.reply(i3.GetBarConfig(payload))
+
+	case "get_binding_modes":
+		return p.reply(i3.GetBindingModes())
+
+	case "get_binding_state":
+		return p.reply(i3.GetBindingState())
+
+	case "get_version":
+		return p.reply(i3.GetVersion())
+
+	case "get_config":
+		return p.reply(i3.GetConfig())
+
+	case "send_tick":
+		return p.reply(i3.SendTick(payload))
+
+	case "sync":
+		var req i3.SyncRequest
+		if err := json.Unmarshal([]byte(payload), &req); err != nil {
+			return fmt.Errorf("parsing sync request: %v", err)
+		}
+		return p.reply(i3.Sync(req))
+
+	case "subscribe":
+		var types []i3.EventType
+		if err := json.Unmarshal([]byte(payload), &types); err != nil {
+			return fmt.Errorf("parsing event types: %v", err)
+		}
+		recv := i3.Subscribe(types...)
+		for recv.Next() {
+			var ev interface{} = json.RawMessage(recv.RawPayload())
+			if tmpl != nil {
+				ev = recv.Event()
+			}
+			if err := p.print(ev); err != nil {
+				recv.Close()
+				return err
+			}
+			if !o.monitor {
+				break
+			}
+		}
+		return recv.Close()
+	}
+	if _, err := strconv.ParseUint(o.msgType, 0, 32); err == nil {
+		return fmt.Errorf("-format is not supported for numeric message types")
+	}
+	return fmt.Errorf("unknown message type %q, see -help", o.msgType)
+}
+
+// sendRaw sends a message of type t and prints i3’s reply as received, like
+// i3-msg(1) does.
+func sendRaw(p *printer, t uint32, payload string) error {
+	reply, err := i3.SendMessage(t, []byte(payload))
+	if err != nil {
+		return err
+	}
+	if err := p.print(json.RawMessage(reply)); err != nil {
+		return err
+	}
+	if t != messageTypes["command"] {
+		return nil
+	}
+	var crs []i3.CommandResult
+	if err := json.Unmarshal(reply, &crs); err != nil {
+		return err
+	}
+	for _, cr := range crs {
+		if !cr.Success {
+			return &exitError{
+				status: 2,
+				err:    fmt.Errorf("command %q unsuccessful: %v", payload, cr.Error),
+			}
+		}
+	}
+	return nil
+}
+
+// socketPath returns the socket path override, if any.
+func (o *options) socketPath() string {
+	if o.socket != "" {
+		return o.socket
+	}
+	return os.Getenv("I3SOCK")
+}
+
+// parseTemplate parses a -format template. Templates can use the json
+// function to print values as JSON.
+func parseTemplate(text string) (*template.Template, error) {
+	return template.New("format").Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+	}).Parse(text)
+}
+
+// printer prints replies as JSON or using a template.
+type printer struct {
+	w     io.Writer
+	tmpl  *template.Template
+	quiet bool // print nothing
+	raw   bool // print compact JSON
+}
+
+// reply prints v unless err is non-nil. Its signature matches the return
+// values of the i3 package’s message functions.
+func (p *printer) reply(v interface{}, err error) error {
+	if err != nil {
+		return err
+	}
+	return p.print(v)
+}
+
+func (p *printer) print(v interface{}) error {
+	if p.quiet {
+		return nil
+	}
+	if p.tmpl != nil {
+		var buf bytes.Buffer
+		if err := p.tmpl.Execute(&buf, v); err != nil {
+			return err
+		}
+		if buf.Len() > 0 && buf.Bytes()[buf.Len()-1] != '\n' {
+			buf.WriteByte('\n')
+		}
+		_, err := p.w.Write(buf.Bytes())
+		return err
+	}
+	if b, ok := v.(json.RawMessage); ok {
+		// Unlike json.Marshal, json.Indent and json.Compact retain
+		// i3’s escaping.
+		var buf bytes.Buffer
+		var err error
+		if p.raw {
+			err = json.Compact(&buf, b)
+		} else {
+			err = json.Indent(&buf, b, "", "  ")
+		}
+		if err != nil {
+			return err
+		}
+		buf.WriteByte('\n')
+		_, err = p.w.Write(buf.Bytes())
+		return err
+	}
+	var (
+		b   []byte
+		err error
+	)
+	if p.raw {
+		b, err = json.Marshal(v)
+	} else {
+		b, err = json.MarshalIndent(v, "", "  ")
+	}
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(p.w, "%s\n", b)
+	return err
+}
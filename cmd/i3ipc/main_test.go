@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"go.i3wm.org/i3/v4/internal/i3test"
+)
+
+func TestRun(t *testing.T) {
+	srv := i3test.NewServer(t, func(typ uint32, payload []byte) []byte {
+		switch typ {
+		case i3test.GetVersion:
+			return []byte(i3test.Version)
+		case i3test.RunCommand:
+			if string(payload) == "nop" {
+				return []byte(`[{"success": true}]`)
+			}
+			return []byte(`[{"success": false, "error": "unknown command"}]`)
+		case i3test.GetMarks:
+			return []byte(`["a", "b"]`)
+		case i3test.GetTree:
+			return []byte(`{"id": 1, "type": "root", "nodes": [{"id": 2, "type": "con", "marks": ["a"], "nodes": []}]}`)
+		case 42:
+			return []byte(`{"answer": 42}`)
+		}
+		return nil
+	})
+
+	for _, tt := range []struct {
+		name       string
+		args       []string
+		want       string
+		wantErr    string
+		wantStatus int
+	}{
+		{
+			name: "Indent",
+			args: []string{"-t", "get_marks"},
+			want: "[\n  \"a\",\n  \"b\"\n]\n",
+		},
+
+		{
+			name: "Raw",
+			args: []string{"-r", "-t", "get_marks"},
+			want: `["a","b"]` + "\n",
+		},
+
+		{
+			name: "Quiet",
+			args: []string{"-q", "-t", "get_marks"},
+			want: "",
+		},
+
+		{
+			name: "Format",
+			args: []string{"-t", "get_marks", "-format", "{{len .}} marks"},
+			want: "2 marks\n",
+		},
+
+		{
+			name: "Command",
+			args: []string{"-r", "nop"},
+			want: `[{"success":true}]` + "\n",
+		},
+
+		{
+			name:       "CommandUnsuccessful",
+			args:       []string{"-r", "bogus"},
+			want:       `[{"success":false,"error":"unknown command"}]` + "\n",
+			wantErr:    `command "bogus" unsuccessful`,
+			wantStatus: 2,
+		},
+
+		{
+			name: "Numeric",
+			args: []string{"-r", "-t", "42"},
+			want: `{"answer":42}` + "\n",
+		},
+
+		{
+			name:    "NumericFormat",
+			args:    []string{"-t", "42", "-format", "{{.}}"},
+			wantErr: "-format is not supported for numeric message types",
+		},
+
+		{
+			name:    "UnknownType",
+			args:    []string{"-t", "get_everything"},
+			wantErr: `unknown message type "get_everything"`,
+		},
+
+		{
+			name:    "UnknownFlag",
+			args:    []string{"-x"},
+			wantErr: "flag provided but not defined: -x",
+		},
+
+		{
+			name: "Subscribe",
+			args: []string{"-r", "-t", "subscribe", `["tick"]`},
+			want: `{"first":true,"payload":""}` + "\n",
+		},
+
+		{
+			name: "Query",
+			args: []string{"query", "-format", "{{.ID}}", "[con_mark=a]"},
+			want: "2\n",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := run(&out, append([]string{"-s", srv.Path}, tt.args...))
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("run(%q): %v", tt.args, err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("run(%q): got error %v, want error containing %q", tt.args, err, tt.wantErr)
+			}
+			var ee *exitError
+			if errors.As(err, &ee) != (tt.wantStatus != 0) || (ee != nil && ee.status != tt.wantStatus) {
+				t.Errorf("run(%q): got error %#v, want exit status %d", tt.args, err, tt.wantStatus)
+			}
+			if got := out.String(); got != tt.want {
+				t.Errorf("run(%q): got output %q, want %q", tt.args, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	"go.i3wm.org/i3/v4"
+)
+
+const queryUsage = `usage: i3ipc [-s <socket>] query [-o json|table] [-format <template>] <expression>
+
+Fetches the layout tree and prints the nodes or fields selected by expression,
+which is either i3 criteria or a path, e.g.:
+
+  i3ipc query '[class="^Firefox$"]'
+  i3ipc query -o table '//workspace[urgent]'
+  i3ipc query '//con[class=Firefox]/rect'
+  i3ipc query -format '{{.ID}}' '/root/output[name=^HDMI]//con[focused]'
+
+See https://pkg.go.dev/go.i3wm.org/i3/v4#Query for the path syntax.
+
+Flags:
+`
+
+// runQuery implements the query subcommand.
+func runQuery(w io.Writer, o *options, args []string) error {
+	fset := flag.NewFlagSet("query", flag.ContinueOnError)
+	fset.Usage = func() {
+		fmt.Fprint(fset.Output(), queryUsage)
+		fset.PrintDefaults()
+	}
+	var (
+		output = fset.String("o", "json", "output format: json (one JSON array) or table")
+		format = fset.String("format", "", "execute this text/template on each result instead")
+	)
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+	if fset.NArg() != 1 {
+		fset.Usage()
+		return fmt.Errorf("expected precisely one query expression, got %d", fset.NArg())
+	}
+	q, err := i3.ParseQuery(fset.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	var tmpl *template.Template
+	if *format != "" {
+		if tmpl, err = parseTemplate(*format); err != nil {
+			return err
+		}
+	}
+
+	tree, err := i3.GetTree()
+	if err != nil {
+		return err
+	}
+	results := q.Select(tree.Root)
+
+	if tmpl != nil {
+		p := &printer{w: w, tmpl: tmpl, quiet: o.quiet}
+		for _, r := range results {
+			if err := p.print(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	switch *output {
+	case "json":
+		p := &printer{w: w, quiet: o.quiet, raw: o.raw}
+		return p.print(results)
+
+	case "table":
+		return printTable(w, results)
+	}
+	return fmt.Errorf("unknown output format %q", *output)
+}
+
+// printTable prints one line per result: the most relevant columns for nodes,
+// or the value for fields.
+func printTable(w io.Writer, results []interface{}) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	header := false
+	for _, r := range results {
+		n, ok := r.(*i3.Node)
+		if !ok {
+			if s, ok := r.(string); ok {
+				fmt.Fprintln(tw, s)
+				continue
+			}
+			b, err := json.Marshal(r)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(tw, "%s\n", b)
+			continue
+		}
+		if !header {
+			fmt.Fprintln(tw, "ID\tTYPE\tLAYOUT\tCLASS\tMARKS\tNAME")
+			header = true
+		}
+		class := n.WindowProperties.Class
+		if class == "" {
+			class = n.AppID
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\n",
+			n.ID,
+			n.Type,
+			n.Layout,
+			class,
+			strings.Join(n.Marks, ","),
+			n.Name)
+	}
+	return tw.Flush()
+}
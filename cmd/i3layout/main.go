@@ -0,0 +1,57 @@
+// Binary i3layout saves the layout of all workspaces whenever it changes, and
+// restores saved layouts on demand (-restore) or when i3 creates a workspace
+// (-auto, once the user switches to the workspace).
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+
+	"go.i3wm.org/i3/v4/layout"
+)
+
+var (
+	dir        = flag.String("dir", "", "directory in which to save layouts (default: $XDG_CONFIG_HOME/i3/layouts)")
+	launchPath = flag.String("launch", "", `path to a JSON file mapping window classes to command lines, e.g. {"URxvt": ["urxvt"]}`)
+	auto       = flag.Bool("auto", false, "restore the saved layout of workspaces when switching to them after i3 created them")
+	restore    = flag.String("restore", "", "if non-empty, restore the saved layout of this workspace and exit")
+)
+
+func main() {
+	flag.Parse()
+	s := &layout.Service{
+		Dir:         *dir,
+		AutoRestore: *auto,
+	}
+	if s.Dir == "" {
+		config, err := os.UserConfigDir()
+		if err != nil {
+			log.Fatal(err)
+		}
+		s.Dir = filepath.Join(config, "i3", "layouts")
+	}
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		log.Fatal(err)
+	}
+	if *launchPath != "" {
+		b, err := os.ReadFile(*launchPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := json.Unmarshal(b, &s.Launch); err != nil {
+			log.Fatalf("%s: %v", *launchPath, err)
+		}
+	}
+	if *restore != "" {
+		if err := s.Restore(*restore); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if err := s.Run(); err != nil {
+		log.Fatal(err)
+	}
+}
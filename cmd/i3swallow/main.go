@@ -0,0 +1,37 @@
+// Binary i3swallow hides terminal windows while the windows of programs
+// started from them are open, and restores them in place afterwards.
+package main
+
+import (
+	"flag"
+	"log"
+	"strings"
+
+	"go.i3wm.org/i3/v4/swallow"
+)
+
+var (
+	terminals = flag.String("terminals", strings.Join(swallow.DefaultTerminals, ","), "comma-separated window classes of terminal emulators")
+	allow     = flag.String("allow", "", "if non-empty, comma-separated window classes which swallow their terminal")
+	deny      = flag.String("deny", "", "comma-separated window classes which never swallow their terminal")
+)
+
+// list splits a comma-separated flag value.
+func list(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+func main() {
+	flag.Parse()
+	s := &swallow.Swallower{
+		Terminals: list(*terminals),
+		Allow:     list(*allow),
+		Deny:      list(*deny),
+	}
+	if err := s.Run(); err != nil {
+		log.Fatal(err)
+	}
+}
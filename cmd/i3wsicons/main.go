@@ -0,0 +1,40 @@
+// Binary i3wsicons renames numbered workspaces to “<num>: <icons>”, where
+// the icons represent the windows on the workspace.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"go.i3wm.org/i3/v4/wsicons"
+)
+
+var (
+	iconsPath = flag.String("icons", "", `path to a JSON file mapping window classes to icons, e.g. {"firefox": ""}`)
+	def       = flag.String("default", "", "icon for windows whose class is not mapped (default: the lower-cased class)")
+	separator = flag.String("separator", " ", "separator between icons")
+	dedupe    = flag.Bool("dedupe", true, "show each icon only once per workspace")
+)
+
+func main() {
+	flag.Parse()
+	r := &wsicons.Renamer{
+		Default:   *def,
+		Separator: *separator,
+		Dedupe:    *dedupe,
+	}
+	if *iconsPath != "" {
+		b, err := os.ReadFile(*iconsPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := json.Unmarshal(b, &r.Icons); err != nil {
+			log.Fatalf("%s: %v", *iconsPath, err)
+		}
+	}
+	if err := r.Run(); err != nil {
+		log.Fatal(err)
+	}
+}
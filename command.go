@@ -2,7 +2,9 @@ package i3
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 )
 
 // CommandResult always contains Success, and command-specific fields where
@@ -19,8 +21,8 @@ type CommandResult struct {
 // IsUnsuccessful is a convenience function which can be used to check if an
 // error is a CommandUnsuccessfulError.
 func IsUnsuccessful(err error) bool {
-	_, ok := err.(*CommandUnsuccessfulError)
-	return ok
+	var cerr *CommandUnsuccessfulError
+	return errors.As(err, &cerr)
 }
 
 // CommandUnsuccessfulError is returned by RunCommand for unsuccessful
@@ -36,6 +38,13 @@ func (e *CommandUnsuccessfulError) Error() string {
 	return fmt.Sprintf("command %q unsuccessful: %v", e.command, e.cr.Error)
 }
 
+// Quote returns s as a double-quoted string argument for i3 commands (and
+// criteria), escaping double quotes and backslashes, e.g. for use in
+// “rename workspace to ” + Quote(name).
+func Quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
 // RunCommand makes i3 run the specified command.
 //
 // Error is non-nil if any CommandResult.Success is not true. See IsUnsuccessful
@@ -12,6 +12,9 @@ import (
 	"strings"
 	"sync"
 	"syscall"
+	"testing"
+
+	"go.i3wm.org/i3/v4/internal/i3test"
 )
 
 func displayLikelyAvailable(display int) bool {
@@ -126,3 +129,177 @@ func launchXvfb(ctx context.Context) (xvfb *exec.Cmd, DISPLAY string, _ error) {
 	}
 	return nil, "", lastErr
 }
+
+// testTree returns a synthetic layout tree for tests which do not need a
+// running i3. It contains the __i3 output with one scratchpad window, and
+// output HDMI-1 with a split workspace (focused Firefox) and a tabbed
+// workspace:
+//
+//	root
+//	├── __i3 → content → __i3_scratch → floating_con → Scratch (1000)
+//	└── HDMI-1
+//	    ├── topdock
+//	    ├── content
+//	    │   ├── 1: www [splith]: XTerm (1001), Firefox (1002, focused)
+//	    │   └── 2 [tabbed]: Emacs (1003), [splitv]: mpv (1004, urgent)
+//	    └── bottomdock
+func testTree() *Node {
+	screen := Rect{Width: 1920, Height: 1080}
+	window := func(id NodeID, class string, wid int64, rect Rect) *Node {
+		return &Node{
+			ID:     id,
+			Name:   class + " window",
+			Type:   Con,
+			Layout: SplitH,
+			Rect:   rect,
+			Window: wid,
+			WindowProperties: WindowProperties{
+				Class:    class,
+				Instance: strings.ToLower(class),
+				Title:    class + " window",
+			},
+			Floating: AutoOff,
+		}
+	}
+	xterm := window(21, "XTerm", 1001, Rect{X: 0, Y: 0, Width: 960, Height: 1080})
+	xterm.Marks = []string{"term"}
+	firefox := window(22, "Firefox", 1002, Rect{X: 960, Y: 0, Width: 960, Height: 1080})
+	firefox.Focused = true
+	emacs := window(31, "Emacs", 1003, Rect{X: 0, Y: 20, Width: 1920, Height: 1060})
+	mpv := window(33, "mpv", 1004, Rect{X: 0, Y: 20, Width: 1920, Height: 1060})
+	mpv.Urgent = true
+	scratch := window(6, "Scratch", 1000, Rect{X: 660, Y: 340, Width: 600, Height: 400})
+	scratch.Floating = UserOn
+	scratch.ScratchpadState = "fresh"
+
+	return &Node{
+		ID:     1,
+		Name:   "root",
+		Type:   Root,
+		Layout: SplitH,
+		Rect:   screen,
+		Focus:  []NodeID{10, 2},
+		Nodes: []*Node{
+			{
+				ID:     2,
+				Name:   "__i3",
+				Type:   OutputNode,
+				Layout: OutputLayout,
+				Focus:  []NodeID{3},
+				Nodes: []*Node{
+					{
+						ID:     3,
+						Name:   "content",
+						Type:   Con,
+						Layout: SplitH,
+						Focus:  []NodeID{4},
+						Nodes: []*Node{
+							{
+								ID:     4,
+								Name:   "__i3_scratch",
+								Type:   WorkspaceNode,
+								Layout: SplitH,
+								Focus:  []NodeID{5},
+								FloatingNodes: []*Node{
+									{
+										ID:       5,
+										Type:     FloatingCon,
+										Layout:   SplitH,
+										Rect:     scratch.Rect,
+										Floating: UserOn,
+										Focus:    []NodeID{6},
+										Nodes:    []*Node{scratch},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			{
+				ID:     10,
+				Name:   "HDMI-1",
+				Type:   OutputNode,
+				Layout: OutputLayout,
+				Rect:   screen,
+				Focus:  []NodeID{12, 11, 13},
+				Nodes: []*Node{
+					{ID: 11, Name: "topdock", Type: DockareaNode, Layout: DockareaLayout},
+					{
+						ID:     12,
+						Name:   "content",
+						Type:   Con,
+						Layout: SplitH,
+						Rect:   screen,
+						Focus:  []NodeID{20, 30},
+						Nodes: []*Node{
+							{
+								ID:     20,
+								Name:   "1: www",
+								Type:   WorkspaceNode,
+								Layout: SplitH,
+								Rect:   screen,
+								Focus:  []NodeID{22, 21},
+								Nodes:  []*Node{xterm, firefox},
+							},
+							{
+								ID:     30,
+								Name:   "2",
+								Type:   WorkspaceNode,
+								Layout: Tabbed,
+								Rect:   screen,
+								Urgent: true,
+								Focus:  []NodeID{31, 32},
+								Nodes: []*Node{
+									emacs,
+									{
+										ID:     32,
+										Type:   Con,
+										Layout: SplitV,
+										Rect:   Rect{X: 0, Y: 20, Width: 1920, Height: 1060},
+										Focus:  []NodeID{33},
+										Nodes:  []*Node{mpv},
+									},
+								},
+							},
+						},
+					},
+					{ID: 13, Name: "bottomdock", Type: DockareaNode, Layout: DockareaLayout},
+				},
+			},
+		},
+	}
+}
+
+// useFakeI3 points the package at a fake i3 (see i3test.NewServer) until the
+// test finishes. Tests which call useFakeI3 must not run in parallel.
+func useFakeI3(t *testing.T, handle func(t uint32, payload []byte) []byte) *i3test.Server {
+	srv := i3test.NewServer(t, handle)
+	origHook := SocketPathHook
+	SocketPathHook = func() (string, error) { return srv.Path, nil }
+	resetConnections()
+	t.Cleanup(func() {
+		SocketPathHook = origHook
+		setVersion(Version{})
+		resetConnections()
+	})
+	return srv
+}
+
+// resetConnections forgets the socket path and closes all pooled connections.
+func resetConnections() {
+	remote.mu.Lock()
+	remote.path = ""
+	remote.mu.Unlock()
+	conns := []*pooledConn{acquireConn()}
+	for len(conns) < cap(pool.conns) {
+		conns = append(conns, acquireConn())
+	}
+	for _, c := range conns {
+		if c.conn != nil {
+			c.conn.Close()
+		}
+		c.sock, c.conn = nil, nil
+		releaseConn(c)
+	}
+}
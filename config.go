@@ -35,3 +35,18 @@ func GetConfig() (Config, error) {
 	err = json.Unmarshal(reply.Payload, &cfg)
 	return cfg, err
 }
+
+// GetConfigRaw is like GetConfig, but additionally returns the JSON object as
+// sent by i3, e.g. for fields which Config does not model.
+//
+// GetConfigRaw is supported in i3 ≥ v4.14 (2017-09-04).
+func GetConfigRaw() (Config, json.RawMessage, error) {
+	reply, err := roundTrip(messageTypeGetConfig, nil)
+	if err != nil {
+		return Config{}, nil, err
+	}
+
+	var cfg Config
+	err = json.Unmarshal(reply.Payload, &cfg)
+	return cfg, reply.Payload, err
+}
@@ -0,0 +1,432 @@
+package i3
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Criteria selects windows in the same way as the criteria of i3 commands,
+// e.g. [class="^Firefox$" title="Inbox"].
+//
+// The following criteria are supported: class, instance, window_role, title,
+// window_type, app_id, con_mark, workspace (all regular expressions, or
+// __focused__ where i3 supports it, i.e. not for window_type, app_id and
+// con_mark), con_id, id, urgent, floating, tiling and all.
+//
+// Like in i3, urgent=latest and urgent=oldest select a single window among
+// all urgent windows of the tree. As i3 does not expose when windows became
+// urgent, the window with the highest and lowest container ID is selected,
+// respectively. Match never matches these values; use FindAll instead.
+//
+// See https://i3wm.org/docs/userguide.html#command_criteria for more details.
+type Criteria struct {
+	terms []criterion
+}
+
+// ParseCriteria parses criteria in i3 syntax, including the surrounding
+// square brackets.
+func ParseCriteria(s string) (*Criteria, error) {
+	s = strings.TrimSpace(s)
+	body, rest, err := scanBrackets(s)
+	if err != nil {
+		return nil, err
+	}
+	if rest != "" {
+		return nil, fmt.Errorf("unexpected %q after criteria", rest)
+	}
+	terms, err := parseCriteria(body, false)
+	if err != nil {
+		return nil, err
+	}
+	return &Criteria{terms: terms}, nil
+}
+
+// MustParseCriteria is like ParseCriteria but panics if s cannot be parsed.
+func MustParseCriteria(s string) *Criteria {
+	c, err := ParseCriteria(s)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// String returns c in i3 syntax, suitable as a prefix of commands.
+func (c *Criteria) String() string {
+	parts := make([]string, len(c.terms))
+	for i, t := range c.terms {
+		parts[i] = t.String()
+	}
+	return "[" + strings.Join(parts, " ") + "]"
+}
+
+// Match returns whether n is a window matching c. As n’s position in the
+// layout tree is unknown, the workspace criterion and __focused__ values never
+// match; use FindAll to evaluate those.
+func (c *Criteria) Match(n *Node) bool {
+	return c.match(n, nil)
+}
+
+// FindAll returns all windows in the tree rooted at root which match c, in
+// the same order as FindChild would visit them.
+func (c *Criteria) FindAll(root *Node) []*Node {
+	ctx := newMatchContext(root)
+	var matches []*Node
+	root.FindChild(func(n *Node) bool {
+		if c.match(n, ctx) {
+			matches = append(matches, n)
+		}
+		return false // visit all nodes
+	})
+	return matches
+}
+
+func (c *Criteria) match(n *Node, ctx *matchContext) bool {
+	containerOnly := true
+	for _, t := range c.terms {
+		if t.key != "con_id" && t.key != "con_mark" {
+			containerOnly = false
+		}
+		if !t.match(n, ctx) {
+			return false
+		}
+	}
+	// Like in i3, only criteria which refer to containers match non-window
+	// containers.
+	return containerOnly || isWindow(n)
+}
+
+// isWindow returns whether n contains an X11 window or a Wayland surface.
+func isWindow(n *Node) bool {
+	return n.Window != 0 || n.AppID != ""
+}
+
+// matchContext provides the layout tree information which some criteria
+// need: the workspace of a node and the focused node.
+type matchContext struct {
+	parents map[*Node]*Node
+	focused *Node
+	urgent  []*Node // urgent windows
+}
+
+func newMatchContext(root *Node) *matchContext {
+	ctx := &matchContext{parents: make(map[*Node]*Node)}
+	var walk func(n *Node)
+	walk = func(n *Node) {
+		if n.Focused {
+			ctx.focused = n
+		}
+		if n.Urgent && isWindow(n) {
+			ctx.urgent = append(ctx.urgent, n)
+		}
+		for _, c := range n.Nodes {
+			ctx.parents[c] = n
+			walk(c)
+		}
+		for _, c := range n.FloatingNodes {
+			ctx.parents[c] = n
+			walk(c)
+		}
+	}
+	walk(root)
+	return ctx
+}
+
+// workspace returns the workspace containing n, or nil.
+func (ctx *matchContext) workspace(n *Node) *Node {
+	for ; n != nil; n = ctx.parents[n] {
+		if n.Type == WorkspaceNode {
+			return n
+		}
+	}
+	return nil
+}
+
+// urgentWindow returns the urgent window with the highest (latest) or lowest
+// container ID, or nil.
+func (ctx *matchContext) urgentWindow(latest bool) *Node {
+	var selected *Node
+	for _, n := range ctx.urgent {
+		if selected == nil || (n.ID > selected.ID) == latest {
+			selected = n
+		}
+	}
+	return selected
+}
+
+// criterion is a single key=value pair of Criteria (or of a query predicate).
+type criterion struct {
+	key      string
+	value    string
+	hasValue bool
+	re       *regexp.Regexp // for regular expression criteria and fields
+	field    []int          // for Node fields, see fieldByName
+}
+
+// criteriaStrings extracts the values to which regular expression criteria
+// are matched.
+var criteriaStrings = map[string]func(*Node) []string{
+	"class":       func(n *Node) []string { return []string{n.WindowProperties.Class} },
+	"instance":    func(n *Node) []string { return []string{n.WindowProperties.Instance} },
+	"window_role": func(n *Node) []string { return []string{n.WindowProperties.Role} },
+	"window_type": func(n *Node) []string { return []string{n.WindowType} },
+	"app_id":      func(n *Node) []string { return []string{n.AppID} },
+	"con_mark":    func(n *Node) []string { return n.Marks },
+	"title": func(n *Node) []string {
+		if n.WindowProperties.Title == "" {
+			return []string{n.Name} // e.g. Wayland windows in sway
+		}
+		return []string{n.WindowProperties.Title}
+	},
+}
+
+// urgentValues maps the values of the urgent criterion which i3 accepts to
+// whether they select the latest (as opposed to the oldest) urgent window.
+var urgentValues = map[string]bool{
+	"latest": true,
+	"newest": true,
+	"recent": true,
+	"last":   true,
+	"oldest": false,
+	"first":  false,
+}
+
+var criteriaFlags = map[string]bool{
+	"urgent":   true,
+	"floating": true,
+	"tiling":   true,
+	"all":      true,
+}
+
+// parseCriteria parses the contents of square brackets. If fields is true,
+// keys which are not i3 criteria refer to Node fields by their JSON name.
+func parseCriteria(s string, fields bool) ([]criterion, error) {
+	var terms []criterion
+	for {
+		s = strings.TrimLeft(s, " \t,")
+		if s == "" {
+			break
+		}
+		end := strings.IndexAny(s, "= \t,")
+		if end == -1 {
+			end = len(s)
+		}
+		t := criterion{key: s[:end]}
+		s = s[end:]
+		if strings.HasPrefix(s, "=") {
+			var err error
+			t.value, s, err = scanValue(s[1:])
+			if err != nil {
+				return nil, err
+			}
+			t.hasValue = true
+		}
+		if err := t.compile(fields); err != nil {
+			return nil, err
+		}
+		terms = append(terms, t)
+	}
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("empty criteria")
+	}
+	return terms, nil
+}
+
+// scanValue returns the (possibly double-quoted) value at the start of s and
+// the remainder of s.
+func scanValue(s string) (value, rest string, _ error) {
+	if !strings.HasPrefix(s, `"`) {
+		end := strings.IndexAny(s, " \t,")
+		if end == -1 {
+			end = len(s)
+		}
+		return s[:end], s[end:], nil
+	}
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && (s[i+1] == '"' || s[i+1] == '\\'):
+			// Like i3, only unescape \" and \\, so that regular
+			// expressions like \w keep working.
+			b.WriteByte(s[i+1])
+			i++
+		case s[i] == '"':
+			return b.String(), s[i+1:], nil
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return "", "", fmt.Errorf("unterminated quoted value %s", s)
+}
+
+// scanBrackets returns the contents of the square brackets at the start of s
+// and the remainder of s, skipping over closing brackets in quoted values.
+func scanBrackets(s string) (body, rest string, _ error) {
+	if !strings.HasPrefix(s, "[") {
+		return "", "", fmt.Errorf("expected [ at %q", s)
+	}
+	quoted := false
+	for i := 1; i < len(s); i++ {
+		switch {
+		case quoted && s[i] == '\\' && i+1 < len(s) && (s[i+1] == '"' || s[i+1] == '\\'):
+			i++
+		case s[i] == '"':
+			quoted = !quoted
+		case !quoted && s[i] == ']':
+			return s[1:i], s[i+1:], nil
+		}
+	}
+	return "", "", fmt.Errorf("missing ] in %q", s)
+}
+
+func (t *criterion) compile(fields bool) error {
+	if _, ok := criteriaStrings[t.key]; ok || t.key == "workspace" {
+		if !t.hasValue {
+			return fmt.Errorf("criterion %s requires a value", t.key)
+		}
+		if t.value == "__focused__" {
+			switch t.key {
+			case "con_mark", "window_type", "app_id":
+				return fmt.Errorf("criterion %s does not support __focused__", t.key)
+			}
+			return nil
+		}
+		var err error
+		t.re, err = regexp.Compile(t.value)
+		return err
+	}
+	switch t.key {
+	case "con_id", "id":
+		if !t.hasValue {
+			return fmt.Errorf("criterion %s requires a value", t.key)
+		}
+		if t.value == "__focused__" && t.key == "con_id" {
+			return nil
+		}
+		if _, err := strconv.ParseInt(t.value, 0, 64); err != nil {
+			return fmt.Errorf("criterion %s: %v", t.key, err)
+		}
+		return nil
+	}
+	if t.key == "urgent" && t.hasValue {
+		if _, ok := urgentValues[strings.ToLower(t.value)]; !ok {
+			return fmt.Errorf("criterion urgent: unknown value %q, expected latest or oldest", t.value)
+		}
+		return nil
+	}
+	if criteriaFlags[t.key] {
+		return nil
+	}
+	if !fields {
+		return fmt.Errorf("unknown criterion %q", t.key)
+	}
+	idx, typ, ok := fieldByName(reflect.TypeOf(Node{}), t.key)
+	if !ok {
+		return fmt.Errorf("unknown criterion or field %q", t.key)
+	}
+	if k := typ.Kind(); k == reflect.Struct || k == reflect.Ptr ||
+		(k == reflect.Slice && typ.Elem().Kind() == reflect.Ptr) {
+		return fmt.Errorf("field %q cannot be compared", t.key)
+	}
+	t.field = idx
+	if t.hasValue {
+		var err error
+		t.re, err = regexp.Compile(t.value)
+		return err
+	}
+	return nil
+}
+
+func (t *criterion) String() string {
+	if !t.hasValue {
+		return t.key
+	}
+	return t.key + "=" + Quote(t.value)
+}
+
+func (t *criterion) match(n *Node, ctx *matchContext) bool {
+	if t.field != nil {
+		return matchField(reflect.ValueOf(n).Elem().FieldByIndex(t.field), t.re)
+	}
+	if t.value == "__focused__" {
+		if ctx == nil || ctx.focused == nil {
+			return false
+		}
+		switch t.key {
+		case "con_id":
+			return n == ctx.focused
+		case "workspace":
+			return ctx.workspace(n) != nil && ctx.workspace(n) == ctx.workspace(ctx.focused)
+		}
+		return criteriaStrings[t.key](n)[0] == criteriaStrings[t.key](ctx.focused)[0]
+	}
+	if strs, ok := criteriaStrings[t.key]; ok {
+		for _, s := range strs(n) {
+			if t.re.MatchString(s) {
+				return true
+			}
+		}
+		return false
+	}
+	switch t.key {
+	case "workspace":
+		if ctx == nil {
+			return false
+		}
+		ws := ctx.workspace(n)
+		return ws != nil && t.re.MatchString(ws.Name)
+	case "con_id":
+		id, _ := strconv.ParseInt(t.value, 0, 64)
+		return n.ID == NodeID(id)
+	case "id":
+		id, _ := strconv.ParseInt(t.value, 0, 64)
+		return n.Window == id
+	case "urgent":
+		if !t.hasValue || !n.Urgent {
+			return n.Urgent
+		}
+		return ctx != nil && n == ctx.urgentWindow(urgentValues[strings.ToLower(t.value)])
+	case "floating":
+		return n.IsFloating() || n.Type == FloatingCon
+	case "tiling":
+		return !n.IsFloating() && n.Type != FloatingCon
+	case "all":
+		return true
+	}
+	return false
+}
+
+// matchField matches a Node field value against re or, if re is nil, tests
+// whether the value is non-zero.
+func matchField(v reflect.Value, re *regexp.Regexp) bool {
+	if re == nil {
+		return !v.IsZero() && (v.Kind() != reflect.Slice || v.Len() > 0)
+	}
+	if v.Kind() == reflect.Slice {
+		for i := 0; i < v.Len(); i++ {
+			if re.MatchString(fmt.Sprint(v.Index(i).Interface())) {
+				return true
+			}
+		}
+		return false
+	}
+	return re.MatchString(fmt.Sprint(v.Interface()))
+}
+
+// fieldByName returns the index sequence and type of the field of struct type
+// t whose JSON name is name.
+func fieldByName(t reflect.Type, name string) ([]int, reflect.Type, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if tag == "-" {
+			continue // not part of i3’s reply, e.g. Node.Raw
+		}
+		if tag == name {
+			return []int{i}, f.Type, true
+		}
+	}
+	return nil, nil, false
+}
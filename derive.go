@@ -0,0 +1,200 @@
+package i3
+
+// WindowOutputEvent is a derived event (see SubscribeDerived) which is sent
+// when a window ends up on a different output, e.g. because its workspace was
+// moved to another output.
+type WindowOutputEvent struct {
+	Container *Node
+	OldOutput string
+	NewOutput string
+}
+
+// WorkspaceEmptyEvent is a derived event (see SubscribeDerived) which is sent
+// when the last window leaves a workspace. Unless the workspace is visible, i3
+// will also have removed the workspace, in which case Workspace refers to the
+// older snapshot.
+type WorkspaceEmptyEvent struct {
+	Workspace *Node
+}
+
+// LayoutChangeEvent is a derived event (see SubscribeDerived) which is sent
+// when the layout of a container (including workspaces) changes, e.g. from
+// splith to splitv.
+type LayoutChangeEvent struct {
+	Container *Node
+	OldLayout Layout
+	NewLayout Layout
+}
+
+// WindowResizeEvent is a derived event (see SubscribeDerived) which is sent
+// when the width or height of a window changes.
+type WindowResizeEvent struct {
+	Container *Node
+	OldRect   Rect
+	NewRect   Rect
+}
+
+// DerivedEventReceiver delivers events which SubscribeDerived synthesizes
+// from successive layout tree snapshots.
+//
+// DerivedEventReceiver is not safe for concurrent use.
+type DerivedEventReceiver struct {
+	recv  *EventReceiver
+	tree  Tree
+	queue []Event
+	ev    Event
+	err   error
+}
+
+// SubscribeDerived returns a DerivedEventReceiver for receiving events which
+// i3 does not send itself: WindowOutputEvent, WorkspaceEmptyEvent,
+// LayoutChangeEvent and WindowResizeEvent.
+//
+// The events are derived by fetching the layout tree (see GetTree) whenever
+// i3 sends a window, workspace or output event, and comparing it with the
+// previous snapshot (see DiffTrees). Hence, derived events are delivered in
+// batches, after the i3 events which caused them.
+//
+// SubscribeDerived is supported in i3 ≥ v4.14 (2017-09-04).
+func SubscribeDerived() *DerivedEventReceiver {
+	return &DerivedEventReceiver{
+		recv: Subscribe(WindowEventType, WorkspaceEventType, OutputEventType, ShutdownEventType),
+	}
+}
+
+// Event returns the most recent event received by a call to Next.
+func (r *DerivedEventReceiver) Event() Event {
+	return r.ev
+}
+
+// Next advances the DerivedEventReceiver to the next derived event, which
+// will then be available through the Event method. It returns false when
+// reaching an error. After Next returns false, the Close method will return
+// the first error.
+func (r *DerivedEventReceiver) Next() bool {
+	for r.err == nil {
+		if len(r.queue) > 0 {
+			r.ev, r.queue = r.queue[0], r.queue[1:]
+			return true
+		}
+		if r.tree.Root == nil {
+			// Changes between this snapshot and the subscription becoming
+			// active will be reflected in the next snapshot.
+			if r.tree, r.err = GetTree(); r.err != nil {
+				break
+			}
+		}
+		if !r.recv.Next() {
+			r.err = r.recv.Close()
+			break
+		}
+		if _, ok := r.recv.Event().(*ShutdownEvent); ok {
+			// Node IDs are not stable across restarts, so start over.
+			r.tree = Tree{}
+			continue
+		}
+		tree, err := GetTree()
+		if err != nil {
+			r.err = err
+			break
+		}
+		r.queue = deriveEvents(r.tree, tree)
+		r.tree = tree
+	}
+	return false
+}
+
+// Close closes the connection to i3.
+func (r *DerivedEventReceiver) Close() error {
+	err := r.recv.Close()
+	if r.err == nil {
+		r.err = err
+	}
+	return r.err
+}
+
+// deriveEvents returns the derived events for the changes between two
+// layout tree snapshots.
+func deriveEvents(before, after Tree) []Event {
+	oldIdx, oldOrder := indexTree(before.Root)
+	newIdx, newOrder := indexTree(after.Root)
+	var events []Event
+	// Windows change their output without moving in the tree when their
+	// workspace moves, so compare the outputs of all windows.
+	for _, n := range newOrder {
+		if !isWindow(n) {
+			continue
+		}
+		if _, ok := oldIdx[n.ID]; !ok {
+			continue
+		}
+		oldOutput := oldIdx.ancestor(n.ID, OutputNode)
+		newOutput := newIdx.ancestor(n.ID, OutputNode)
+		if oldOutput != nil && newOutput != nil && oldOutput.Name != newOutput.Name {
+			events = append(events, &WindowOutputEvent{
+				Container: n,
+				OldOutput: oldOutput.Name,
+				NewOutput: newOutput.Name,
+			})
+		}
+	}
+	for _, c := range DiffTrees(before, after) {
+		switch c.Kind {
+		case LayoutChanged:
+			if isWindow(c.New) {
+				continue // the layout of windows is meaningless
+			}
+			events = append(events, &LayoutChangeEvent{
+				Container: c.New,
+				OldLayout: c.Old.Layout,
+				NewLayout: c.New.Layout,
+			})
+
+		case RectChanged:
+			if !isWindow(c.New) ||
+				(c.Old.Rect.Width == c.New.Rect.Width && c.Old.Rect.Height == c.New.Rect.Height) {
+				continue
+			}
+			events = append(events, &WindowResizeEvent{
+				Container: c.New,
+				OldRect:   c.Old.Rect,
+				NewRect:   c.New.Rect,
+			})
+		}
+	}
+
+	// Workspaces which are removed when their last window leaves appear as
+	// removed in DiffTrees, so compare window counts of all old workspaces.
+	for _, ws := range oldOrder {
+		if ws.Type != WorkspaceNode || !hasWindow(ws) {
+			continue
+		}
+		if ne, ok := newIdx[ws.ID]; ok {
+			if hasWindow(ne.node) {
+				continue
+			}
+			ws = ne.node
+		}
+		events = append(events, &WorkspaceEmptyEvent{Workspace: ws})
+	}
+	return events
+}
+
+// ancestor returns the closest ancestor (or self) of the node with the
+// specified id which has type t, or nil.
+func (idx treeIndex) ancestor(id NodeID, t NodeType) *Node {
+	for e, ok := idx[id]; ok; e, ok = idx[e.parentID()] {
+		if e.node.Type == t {
+			return e.node
+		}
+		if e.parent == nil {
+			break
+		}
+	}
+	return nil
+}
+
+// hasWindow returns whether n or any of its descendants is a window.
+func hasWindow(n *Node) bool {
+	return n.FindChild(isWindow) != nil
+}
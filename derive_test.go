@@ -0,0 +1,51 @@
+package i3
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDeriveEvents(t *testing.T) {
+	t.Parallel()
+
+	before := Tree{Root: testTree()}
+	root := testTree()
+	find := func(id NodeID) *Node {
+		return root.FindChild(func(n *Node) bool { return n.ID == id })
+	}
+	// Move workspace 1 to a new output DP-1:
+	hdmiContent, ws1 := find(12), find(20)
+	hdmiContent.Nodes = hdmiContent.Nodes[1:]
+	root.Nodes = append(root.Nodes, &Node{
+		ID:    40,
+		Name:  "DP-1",
+		Type:  OutputNode,
+		Nodes: []*Node{{ID: 41, Name: "content", Type: Con, Nodes: []*Node{ws1}}},
+	})
+	// Close the only window of workspace 2’s split container, which is
+	// then closed as well, leaving workspace 2 empty:
+	ws2 := find(30)
+	ws2.Nodes = nil
+	ws2.Layout = SplitV
+	// Resize XTerm, move Firefox (without resizing):
+	xterm, firefox := find(21), find(22)
+	xterm.Rect.Width = 500
+	firefox.Rect.X = 500
+
+	got := deriveEvents(before, Tree{Root: root})
+	want := []Event{
+		&WindowOutputEvent{Container: xterm, OldOutput: "HDMI-1", NewOutput: "DP-1"},
+		&WindowOutputEvent{Container: firefox, OldOutput: "HDMI-1", NewOutput: "DP-1"},
+		&LayoutChangeEvent{Container: ws2, OldLayout: Tabbed, NewLayout: SplitV},
+		&WindowResizeEvent{
+			Container: xterm,
+			OldRect:   Rect{Width: 960, Height: 1080},
+			NewRect:   Rect{Width: 500, Height: 1080},
+		},
+		&WorkspaceEmptyEvent{Workspace: ws2},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("unexpected derived events: (-want +got)\n%s", diff)
+	}
+}
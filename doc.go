@@ -19,4 +19,10 @@
 // which they were introduced. Under the covers, they use AtLeast, so they
 // return a helpful error message at runtime if the running i3 version is too
 // old.
+//
+// Subpackages build on this package, e.g. bar (workspace buttons like
+// i3bar’s), mru (most recently used windows), autotile, wsicons, scratchpad,
+// swallow, layout (saving and restoring layouts) and metrics (IPC metrics in
+// the Prometheus text format). Binary i3ipc (in cmd/i3ipc) is an i3-msg(1)
+// replacement.
 package i3
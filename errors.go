@@ -0,0 +1,73 @@
+package i3
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNotConnected is returned when a message cannot be sent because there is
+// no connection to i3 and re-connecting failed. The returned error wraps
+// ErrNotConnected as well as the underlying error, e.g. a *net.OpError.
+var ErrNotConnected = errors.New("not connected")
+
+// VersionError is returned when the running i3 version does not support a
+// message or event type, see AtLeast.
+type VersionError struct {
+	Have Version // the running version
+	Want Version // the minimum required version (Major and Minor only)
+}
+
+func (e *VersionError) Error() string {
+	return fmt.Sprintf("i3 version too old: got %d.%d, want ≥ %d.%d", e.Have.Major, e.Have.Minor, e.Want.Major, e.Want.Minor)
+}
+
+// SubscribeError is returned when subscribing to events fails, either because
+// i3 could not be reached (Err is non-nil and usually wraps ErrNotConnected)
+// or because i3 rejected the subscription.
+type SubscribeError struct {
+	Types []EventType
+	Err   error
+}
+
+func (e *SubscribeError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("could not subscribe to %v: %v", e.Types, e.Err)
+	}
+	return fmt.Sprintf("could not subscribe to %v, check the i3 log", e.Types)
+}
+
+func (e *SubscribeError) Unwrap() error {
+	return e.Err
+}
+
+// ProtocolError is returned when a message received from i3 violates the IPC
+// protocol. The connection is closed, as it can no longer be used, and
+// re-established by the next request.
+type ProtocolError struct {
+	Reason string
+	Err    error // underlying error, if any, e.g. io.ErrUnexpectedEOF
+}
+
+func (e *ProtocolError) Error() string {
+	if e.Err != nil {
+		return "i3 IPC protocol error: " + e.Reason + ": " + e.Err.Error()
+	}
+	return "i3 IPC protocol error: " + e.Reason
+}
+
+func (e *ProtocolError) Unwrap() error {
+	return e.Err
+}
+
+// UnknownEventError describes an event of a type which this package does not
+// implement. EventReceiver does not fail on such events, but delivers them as
+// *RawEvent, whose Err method returns an UnknownEventError for code which
+// treats them as errors.
+type UnknownEventError struct {
+	Type    uint32 // without the event flag (highest bit)
+	Payload []byte
+}
+
+func (e *UnknownEventError) Error() string {
+	return fmt.Sprintf("unknown event type %d", e.Type)
+}
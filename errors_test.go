@@ -0,0 +1,113 @@
+package i3
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestErrors(t *testing.T) {
+	t.Parallel()
+
+	t.Run("NotConnected", func(t *testing.T) {
+		var s *socket
+		if _, err := s.roundTrip(messageTypeGetTree, nil); !errors.Is(err, ErrNotConnected) {
+			t.Errorf("roundTrip on nil socket: got %v, want ErrNotConnected", err)
+		}
+	})
+
+	t.Run("Subscribe", func(t *testing.T) {
+		var err error = &SubscribeError{Types: []EventType{WindowEventType}, Err: io.EOF}
+		if !errors.Is(err, io.EOF) {
+			t.Errorf("SubscribeError does not wrap its underlying error")
+		}
+		var serr *SubscribeError
+		if !errors.As(err, &serr) || serr.Types[0] != WindowEventType {
+			t.Errorf("errors.As(%v, *SubscribeError) failed", err)
+		}
+	})
+
+	t.Run("Protocol", func(t *testing.T) {
+		var err error = &ProtocolError{Reason: "malformed reply", Err: io.ErrUnexpectedEOF}
+		if !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("ProtocolError does not wrap its underlying error")
+		}
+	})
+
+	t.Run("Version", func(t *testing.T) {
+		err := &VersionError{Have: Version{Major: 4, Minor: 13}, Want: Version{Major: 4, Minor: 15}}
+		if got, want := err.Error(), "i3 version too old: got 4.13, want ≥ 4.15"; got != want {
+			t.Errorf("Error() = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("Unsuccessful", func(t *testing.T) {
+		err := &CommandUnsuccessfulError{command: "norp"}
+		if !IsUnsuccessful(fmt.Errorf("switching workspace: %w", err)) {
+			t.Errorf("IsUnsuccessful does not detect wrapped errors")
+		}
+	})
+}
+
+func TestErrorsReturned(t *testing.T) {
+	// Not parallel: modifies SocketPathHook, reconnectTimeout and the cached
+	// version.
+	origHook, origTimeout := SocketPathHook, reconnectTimeout
+	defer func() {
+		SocketPathHook, reconnectTimeout = origHook, origTimeout
+		setVersion(Version{})
+	}()
+
+	t.Run("NotConnected", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "nonexistent")
+		SocketPathHook = func() (string, error) { return path, nil }
+		reconnectTimeout = 50 * time.Millisecond
+		_, err := SendMessage(uint32(messageTypeGetVersion), nil)
+		if !errors.Is(err, ErrNotConnected) {
+			t.Errorf("SendMessage: got %v, want ErrNotConnected", err)
+		}
+		var operr *net.OpError
+		if !errors.As(err, &operr) {
+			t.Errorf("SendMessage: %v does not wrap the dial error", err)
+		}
+	})
+
+	t.Run("Subscribe", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "nonexistent")
+		SocketPathHook = func() (string, error) { return path, nil }
+		reconnectTimeout = 50 * time.Millisecond
+		setVersion(Version{Major: 4, Minor: 24}) // skip GET_VERSION
+		recv := Subscribe(WindowEventType)
+		if recv.Next() {
+			t.Fatalf("Next unexpectedly succeeded")
+		}
+		err := recv.Close()
+		if !errors.Is(err, ErrNotConnected) {
+			t.Errorf("Subscribe: got %v, want ErrNotConnected", err)
+		}
+		var serr *SubscribeError
+		if !errors.As(err, &serr) {
+			t.Errorf("Subscribe: got %v, want *SubscribeError", err)
+		}
+		var operr *net.OpError
+		if !errors.As(err, &operr) {
+			t.Errorf("Subscribe: %v does not wrap the dial error", err)
+		}
+	})
+
+	t.Run("Version", func(t *testing.T) {
+		setVersion(Version{Major: 4, Minor: 13})
+		err := AtLeast(4, 15)
+		var verr *VersionError
+		if !errors.As(err, &verr) {
+			t.Fatalf("AtLeast(4, 15): got %v, want *VersionError", err)
+		}
+		if verr.Have.Minor != 13 || verr.Want.Minor != 15 {
+			t.Errorf("AtLeast(4, 15): unexpected %+v", verr)
+		}
+	})
+}
@@ -0,0 +1,245 @@
+package i3
+
+// Direction indicates a direction in which to look for neighbors.
+type Direction string
+
+// The directions have the same names as in i3 commands, e.g. “focus left”:
+const (
+	Left  Direction = "left"
+	Right Direction = "right"
+	Up    Direction = "up"
+	Down  Direction = "down"
+)
+
+// Contains returns whether the point (x, y) lies within r.
+func (r Rect) Contains(x, y int64) bool {
+	return x >= r.X && x < r.X+r.Width && y >= r.Y && y < r.Y+r.Height
+}
+
+// ContainsRect returns whether o lies entirely within r.
+func (r Rect) ContainsRect(o Rect) bool {
+	return o.X >= r.X && o.X+o.Width <= r.X+r.Width &&
+		o.Y >= r.Y && o.Y+o.Height <= r.Y+r.Height
+}
+
+// Intersect returns the largest rectangle contained in both r and o, or the
+// zero Rect if they do not overlap.
+func (r Rect) Intersect(o Rect) Rect {
+	x0, y0 := max(r.X, o.X), max(r.Y, o.Y)
+	x1, y1 := min(r.X+r.Width, o.X+o.Width), min(r.Y+r.Height, o.Y+o.Height)
+	if x0 >= x1 || y0 >= y1 {
+		return Rect{}
+	}
+	return Rect{X: x0, Y: y0, Width: x1 - x0, Height: y1 - y0}
+}
+
+// Overlaps returns whether r and o have a non-empty intersection.
+func (r Rect) Overlaps(o Rect) bool {
+	return r.Intersect(o) != Rect{}
+}
+
+// AbsoluteWindowRect returns WindowRect relative to the X11 display instead
+// of relative to Rect.
+func (n *Node) AbsoluteWindowRect() Rect {
+	r := n.WindowRect
+	r.X += n.Rect.X
+	r.Y += n.Rect.Y
+	return r
+}
+
+// AbsoluteDecoRect returns DecoRect relative to the X11 display instead of
+// relative to Rect.
+func (n *Node) AbsoluteDecoRect() Rect {
+	r := n.DecoRect
+	r.X += n.Rect.X
+	r.Y += n.Rect.Y
+	return r
+}
+
+// VisibleWindows returns the windows within n which are not hidden: of the
+// children of tabbed and stacked containers, only the focused child is
+// visible, and if a window is fullscreen, only that window is visible.
+//
+// VisibleWindows does not consider whether n itself is visible, e.g. whether
+// n is on the visible workspace of its output.
+func (n *Node) VisibleWindows() []*Node {
+	fullscreen := n.FindChild(func(c *Node) bool {
+		return c != n && c.Type != WorkspaceNode && c.FullscreenMode != FullscreenNone
+	})
+	if fullscreen != nil {
+		n = fullscreen
+	}
+	var windows []*Node
+	var walk func(n *Node)
+	walk = func(n *Node) {
+		if isWindow(n) {
+			windows = append(windows, n)
+		}
+		children := n.Nodes
+		if n.Layout == Tabbed || n.Layout == Stacked {
+			children = nil
+			if c := n.focusedChild(); c != nil {
+				children = []*Node{c}
+			}
+		}
+		for _, c := range children {
+			walk(c)
+		}
+		for _, c := range n.FloatingNodes {
+			walk(c)
+		}
+	}
+	walk(n)
+	return windows
+}
+
+// focusedChild returns the tiling child of n which was focused most
+// recently.
+func (n *Node) focusedChild() *Node {
+	for _, id := range n.Focus {
+		for _, c := range n.Nodes {
+			if c.ID == id {
+				return c
+			}
+		}
+	}
+	if len(n.Nodes) > 0 {
+		return n.Nodes[0]
+	}
+	return nil
+}
+
+// VisibleWorkspace returns the workspace which is currently visible on the
+// output with the specified name, or nil.
+func (t Tree) VisibleWorkspace(output string) *Node {
+	for _, o := range t.Root.Nodes {
+		if o.Type != OutputNode || o.Name != output {
+			continue
+		}
+		for _, content := range o.Nodes {
+			if content.Type != Con {
+				continue
+			}
+			// The visible workspace is the most recently focused one.
+			return content.focusedChild()
+		}
+	}
+	return nil
+}
+
+// VisibleWindowsOn returns the visible windows (see Node.VisibleWindows) of
+// the visible workspace of the output with the specified name.
+func (t Tree) VisibleWindowsOn(output string) []*Node {
+	ws := t.VisibleWorkspace(output)
+	if ws == nil {
+		return nil
+	}
+	return ws.VisibleWindows()
+}
+
+// Neighbor returns the visible window which is closest to window n in
+// direction dir, considering the visible workspaces of all outputs. Tiling
+// windows are only neighbors of tiling windows, and floating windows only of
+// floating windows. Neighbor returns nil if there is no window in direction
+// dir.
+func (t Tree) Neighbor(n *Node, dir Direction) *Node {
+	idx, _ := indexTree(t.Root)
+	floating := func(n *Node) bool {
+		return n.IsFloating() || idx.ancestor(n.ID, FloatingCon) != nil
+	}
+	var (
+		candidates []*Node
+		rects      []Rect
+	)
+	for _, o := range t.Root.Nodes {
+		if o.Type != OutputNode || o.Name == "__i3" {
+			continue
+		}
+		for _, w := range t.VisibleWindowsOn(o.Name) {
+			if w.ID == n.ID || floating(w) != floating(n) {
+				continue
+			}
+			candidates = append(candidates, w)
+			rects = append(rects, w.Rect)
+		}
+	}
+	if i := neighbor(n.Rect, rects, dir); i != -1 {
+		return candidates[i]
+	}
+	return nil
+}
+
+// OutputAt returns the active output containing the point (x, y), or nil.
+func OutputAt(outputs []Output, x, y int64) *Output {
+	for i, o := range outputs {
+		if o.Active && o.Rect.Contains(x, y) {
+			return &outputs[i]
+		}
+	}
+	return nil
+}
+
+// OutputNeighbor returns the active output which is closest to output o in
+// direction dir, or nil if there is none.
+func OutputNeighbor(outputs []Output, o Output, dir Direction) *Output {
+	var (
+		candidates []int
+		rects      []Rect
+	)
+	for i, c := range outputs {
+		if !c.Active || c.Name == o.Name {
+			continue
+		}
+		candidates = append(candidates, i)
+		rects = append(rects, c.Rect)
+	}
+	if i := neighbor(o.Rect, rects, dir); i != -1 {
+		return &outputs[candidates[i]]
+	}
+	return nil
+}
+
+// neighbor returns the index of the rectangle in candidates which is closest
+// to r in direction dir, or -1. Rectangles which overlap r on the axis
+// perpendicular to dir are preferred; among those, the one with the smallest
+// gap wins, with ties broken by the largest overlap.
+func neighbor(r Rect, candidates []Rect, dir Direction) int {
+	best, bestOverlapping := -1, false
+	var bestGap, bestOverlap int64
+	for i, c := range candidates {
+		var gap, overlap int64
+		switch dir {
+		case Left:
+			gap = r.X - (c.X + c.Width)
+			overlap = min(r.Y+r.Height, c.Y+c.Height) - max(r.Y, c.Y)
+		case Right:
+			gap = c.X - (r.X + r.Width)
+			overlap = min(r.Y+r.Height, c.Y+c.Height) - max(r.Y, c.Y)
+		case Up:
+			gap = r.Y - (c.Y + c.Height)
+			overlap = min(r.X+r.Width, c.X+c.Width) - max(r.X, c.X)
+		case Down:
+			gap = c.Y - (r.Y + r.Height)
+			overlap = min(r.X+r.Width, c.X+c.Width) - max(r.X, c.X)
+		default:
+			return -1
+		}
+		if gap < 0 {
+			continue // not (entirely) in direction dir
+		}
+		overlapping := overlap > 0
+		if !overlapping {
+			// Without overlap, the distance on the perpendicular axis
+			// counts as well.
+			gap -= overlap
+		}
+		switch {
+		case best == -1,
+			overlapping && !bestOverlapping,
+			overlapping == bestOverlapping && gap < bestGap,
+			overlapping == bestOverlapping && gap == bestGap && overlap > bestOverlap:
+			best, bestOverlapping, bestGap, bestOverlap = i, overlapping, gap, overlap
+		}
+	}
+	return best
+}
@@ -0,0 +1,115 @@
+package i3
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRect(t *testing.T) {
+	t.Parallel()
+
+	r := Rect{X: 10, Y: 10, Width: 100, Height: 50}
+	if !r.Contains(10, 10) || r.Contains(110, 10) || r.Contains(50, 60) {
+		t.Errorf("unexpected Contains results for %v", r)
+	}
+	if !r.ContainsRect(Rect{X: 20, Y: 20, Width: 90, Height: 40}) {
+		t.Errorf("%v unexpectedly does not contain inner rect", r)
+	}
+	if r.ContainsRect(Rect{X: 20, Y: 20, Width: 91, Height: 40}) {
+		t.Errorf("%v unexpectedly contains overlapping rect", r)
+	}
+	if got, want := r.Intersect(Rect{X: 100, Y: 0, Width: 50, Height: 20}), (Rect{X: 100, Y: 10, Width: 10, Height: 10}); got != want {
+		t.Errorf("Intersect = %v, want %v", got, want)
+	}
+	if r.Overlaps(Rect{X: 110, Y: 10, Width: 10, Height: 10}) {
+		t.Errorf("adjacent rects unexpectedly overlap")
+	}
+}
+
+func TestVisibleWindows(t *testing.T) {
+	t.Parallel()
+
+	tree := Tree{Root: testTree()}
+	if diff := cmp.Diff([]NodeID{21, 22}, nodeIDs(tree.VisibleWindowsOn("HDMI-1"))); diff != "" {
+		t.Errorf("VisibleWindowsOn(HDMI-1): (-want +got)\n%s", diff)
+	}
+	ws2 := tree.Root.FindChild(func(n *Node) bool { return n.ID == 30 })
+	// Only the focused tab is visible:
+	if diff := cmp.Diff([]NodeID{31}, nodeIDs(ws2.VisibleWindows())); diff != "" {
+		t.Errorf("VisibleWindows(tabbed): (-want +got)\n%s", diff)
+	}
+	ws2.Focus = []NodeID{32, 31}
+	if diff := cmp.Diff([]NodeID{33}, nodeIDs(ws2.VisibleWindows())); diff != "" {
+		t.Errorf("VisibleWindows(tabbed): (-want +got)\n%s", diff)
+	}
+	ws1 := tree.Root.FindChild(func(n *Node) bool { return n.ID == 20 })
+	ws1.Nodes[0].FullscreenMode = FullscreenOutput
+	if diff := cmp.Diff([]NodeID{21}, nodeIDs(ws1.VisibleWindows())); diff != "" {
+		t.Errorf("VisibleWindows(fullscreen): (-want +got)\n%s", diff)
+	}
+}
+
+func TestNeighbor(t *testing.T) {
+	t.Parallel()
+
+	tree := Tree{Root: testTree()}
+	xterm := tree.Root.FindChild(func(n *Node) bool { return n.ID == 21 })
+	if got := tree.Neighbor(xterm, Right); got == nil || got.ID != 22 {
+		t.Errorf("Neighbor(XTerm, right) = %v, want Firefox", got)
+	}
+	for _, dir := range []Direction{Left, Up, Down} {
+		if got := tree.Neighbor(xterm, dir); got != nil {
+			t.Errorf("Neighbor(XTerm, %s) = %v, want nil", dir, got)
+		}
+	}
+}
+
+func TestOutputs(t *testing.T) {
+	t.Parallel()
+
+	// ┌──────┐┌─────────┐
+	// │ DP-1 ││         │
+	// └──────┘│ HDMI-1  │
+	// ┌──────┐│         │
+	// │ DP-2 │└─────────┘
+	// └──────┘
+	outputs := []Output{
+		{Name: "xroot-0", Rect: Rect{Width: 3840, Height: 2160}},
+		{Name: "DP-1", Active: true, Rect: Rect{X: 0, Y: 0, Width: 1920, Height: 1080}},
+		{Name: "DP-2", Active: true, Rect: Rect{X: 0, Y: 1080, Width: 1920, Height: 1080}},
+		{Name: "HDMI-1", Active: true, Rect: Rect{X: 1920, Y: 0, Width: 1920, Height: 1200}},
+	}
+	if got := OutputAt(outputs, 100, 1500); got == nil || got.Name != "DP-2" {
+		t.Errorf("OutputAt(100, 1500) = %v, want DP-2", got)
+	}
+	if got := OutputAt(outputs, 3000, 1500); got != nil {
+		t.Errorf("OutputAt(3000, 1500) = %v, want nil", got)
+	}
+	for _, tt := range []struct {
+		from string
+		dir  Direction
+		want string
+	}{
+		{"DP-1", Down, "DP-2"},
+		{"DP-2", Up, "DP-1"},
+		{"DP-1", Right, "HDMI-1"},
+		{"DP-2", Right, "HDMI-1"},
+		{"HDMI-1", Left, "DP-1"},
+		{"DP-1", Left, ""},
+	} {
+		var from Output
+		for _, o := range outputs {
+			if o.Name == tt.from {
+				from = o
+			}
+		}
+		got := ""
+		if o := OutputNeighbor(outputs, from, tt.dir); o != nil {
+			got = o.Name
+		}
+		if got != tt.want {
+			t.Errorf("OutputNeighbor(%s, %s) = %q, want %q", tt.from, tt.dir, got, tt.want)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package i3
 import (
 	"sync"
 
+	"github.com/BurntSushi/xgb/xproto"
 	"github.com/BurntSushi/xgbutil"
 	"github.com/BurntSushi/xgbutil/xprop"
 )
@@ -42,3 +43,18 @@ var IsRunningHook = func() bool {
 func i3Running() bool {
 	return IsRunningHook()
 }
+
+// WindowPID returns the process ID which the X11 window with the specified ID
+// (see Node.Window) stored in its _NET_WM_PID property.
+func WindowPID(window int64) (int, error) {
+	xu, err := xgbutil.NewConn()
+	if err != nil {
+		return 0, err
+	}
+	defer xu.Conn().Close()
+	num, err := xprop.PropValNum(xprop.GetProperty(xu, xproto.Window(window), "_NET_WM_PID"))
+	if err != nil {
+		return 0, err
+	}
+	return int(num), nil
+}
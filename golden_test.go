@@ -2,6 +2,7 @@ package i3
 
 import (
 	"context"
+	"encoding/json"
 	"io/ioutil"
 	"os"
 	"os/exec"
@@ -10,6 +11,7 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 )
 
 // TestGoldensSubprocess runs in a process which has been started with
@@ -85,6 +87,10 @@ func TestGoldensSubprocess(t *testing.T) {
 			Font:                 "fixed",
 			WorkspaceButtons:     true,
 			BindingModeIndicator: true,
+			I3barCommand:         ":",
+			HiddenState:          "hide",
+			Modifier:             64, // Mod4
+			TrayPadding:          2,
 			Colors: BarConfigColors{
 				Background:                  "#000000",
 				Statusline:                  "#ffffff",
@@ -109,7 +115,7 @@ func TestGoldensSubprocess(t *testing.T) {
 				BindingModeBorder:           "#ffffff",
 			},
 		}
-		if diff := cmp.Diff(want, got); diff != "" {
+		if diff := cmp.Diff(want, got, cmpopts.EquateEmpty()); diff != "" {
 			t.Fatalf("unexpected GetBarConfig reply: (-want +got)\n%s", diff)
 		}
 	})
@@ -297,3 +303,42 @@ func TestGoldens(t *testing.T) {
 		t.Fatal(err.Error())
 	}
 }
+
+// TestBarConfigGoldens decodes replies of i3 versions older than the one
+// TestGoldens runs.
+func TestBarConfigGoldens(t *testing.T) {
+	t.Parallel()
+
+	b, err := ioutil.ReadFile("testdata/bar_config-4.11.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got BarConfig
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := BarConfig{
+		ID:                   "bar-0",
+		Mode:                 "dock",
+		Position:             "bottom",
+		StatusCommand:        "i3status",
+		Font:                 "fixed",
+		WorkspaceButtons:     true,
+		BindingModeIndicator: true,
+		HiddenState:          "hide",
+		Modifier:             64, // Mod4
+		TrayPadding:          2,
+		TrayOutputs:          []string{"primary"},
+		Colors: BarConfigColors{
+			Background:                 "#000000",
+			Statusline:                 "#ffffff",
+			Separator:                  "#666666",
+			FocusedWorkspaceText:       "#ffffff",
+			FocusedWorkspaceBackground: "#285577",
+			FocusedWorkspaceBorder:     "#4c7899",
+		},
+	}
+	if diff := cmp.Diff(want, got, cmpopts.EquateEmpty()); diff != "" {
+		t.Fatalf("unexpected BarConfig: (-want +got)\n%s", diff)
+	}
+}
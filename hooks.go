@@ -0,0 +1,109 @@
+package i3
+
+import (
+	"strconv"
+	"time"
+)
+
+// RoundTrip describes a request sent to i3 and, once received, its reply.
+type RoundTrip struct {
+	Type        uint32    // message type
+	Name        string    // i3-msg(1) name of the message type, e.g. "get_tree"
+	RequestSize int       // payload size in bytes
+	Start       time.Time // before waiting for a connection
+
+	// The following fields are set before RoundTripEnd is called.
+	ReplySize int   // payload size in bytes
+	Err       error // as returned to the caller
+}
+
+// Hooks observes the IPC layer, e.g. to export metrics or record traces.
+// Hooks methods are called synchronously (and concurrently, from all
+// goroutines which talk to i3), so they must return quickly.
+type Hooks interface {
+	// RoundTripStart is called before a request is sent. RoundTripEnd is
+	// called with the same *RoundTrip after the reply was received (or
+	// the request failed), so implementations can use the pointer to
+	// correlate both calls. Each request of a Batch is reported
+	// separately, from before Batch.Do waits for a connection until all
+	// replies of the batch were received.
+	RoundTripStart(rt *RoundTrip)
+	RoundTripEnd(rt *RoundTrip)
+
+	// PoolWaiting is called with the number of requests waiting for a pooled
+	// connection (see PoolSize) whenever that number changes.
+	PoolWaiting(waiting int)
+
+	// EventReceived is called for every event an EventReceiver receives.
+	EventReceived(t EventType, payloadSize int)
+
+	// Reconnect is called before every attempt to re-establish a broken
+	// connection to i3, whether for requests or for an EventReceiver. err
+	// is the error which broke the connection or made the previous attempt
+	// fail.
+	Reconnect(attempt int, err error)
+}
+
+// IPCHooks, if non-nil, is notified about requests, events and reconnects.
+// Set IPCHooks before sending the first request; changing it concurrently is
+// not safe.
+var IPCHooks Hooks
+
+// startRoundTrip calls IPCHooks.RoundTripStart and returns the *RoundTrip to
+// pass to endRoundTrip, or nil if IPCHooks is nil.
+func startRoundTrip(t messageType, payload []byte) *RoundTrip {
+	if IPCHooks == nil {
+		return nil
+	}
+	name, ok := messageNames[t]
+	if !ok {
+		name = strconv.FormatUint(uint64(t), 10)
+	}
+	rt := &RoundTrip{
+		Type:        uint32(t),
+		Name:        name,
+		RequestSize: len(payload),
+		Start:       time.Now(),
+	}
+	IPCHooks.RoundTripStart(rt)
+	return rt
+}
+
+func endRoundTrip(rt *RoundTrip, replySize int, err error) {
+	if rt == nil {
+		return
+	}
+	rt.ReplySize = replySize
+	rt.Err = err
+	IPCHooks.RoundTripEnd(rt)
+}
+
+func hookReconnect(attempt int, err error) {
+	if IPCHooks != nil {
+		IPCHooks.Reconnect(attempt, err)
+	}
+}
+
+// eventTypes maps the event reply types to their names.
+var eventTypes = map[eventReplyType]EventType{
+	eventReplyTypeWorkspace:       WorkspaceEventType,
+	eventReplyTypeOutput:          OutputEventType,
+	eventReplyTypeMode:            ModeEventType,
+	eventReplyTypeWindow:          WindowEventType,
+	eventReplyTypeBarconfigUpdate: BarconfigUpdateEventType,
+	eventReplyTypeBinding:         BindingEventType,
+	eventReplyTypeShutdown:        ShutdownEventType,
+	eventReplyTypeTick:            TickEventType,
+	eventReplyTypeBarStateUpdate:  BarStateUpdateEventType,
+}
+
+func hookEvent(t uint32, payloadSize int) {
+	if IPCHooks == nil {
+		return
+	}
+	name, ok := eventTypes[eventReplyType(t)]
+	if !ok {
+		name = EventType(strconv.FormatUint(uint64(t), 10))
+	}
+	IPCHooks.EventReceived(name, payloadSize)
+}
@@ -0,0 +1,27 @@
+// Package daemon contains the event loop which the long-running packages of
+// this module (autotile, layout, mru,
+// swallow and wsicons) share.
+package daemon
+
+import "go.i3wm.org/i3/v4"
+
+// Run subscribes to the specified event types and calls handle for every
+// event until handle returns an error or the subscription fails. Run
+// additionally subscribes to shutdown events and returns nil when i3 exits,
+// whereas it keeps running when i3 restarts.
+//
+// Run is supported in i3 ≥ v4.14 (2017-09-04).
+func Run(handle func(ev i3.Event) error, types ...i3.EventType) error {
+	types = append(types[:len(types):len(types)], i3.ShutdownEventType)
+	recv := i3.Subscribe(types...)
+	defer recv.Close()
+	for recv.Next() {
+		if ev, ok := recv.Event().(*i3.ShutdownEvent); ok && ev.Change == "exit" {
+			return nil
+		}
+		if err := handle(recv.Event()); err != nil {
+			return err
+		}
+	}
+	return recv.Close()
+}
@@ -0,0 +1,202 @@
+// Package i3test implements a fake i3 which speaks the IPC protocol on a UNIX
+// socket, so that programs can be tested against scripted replies without
+// running i3 (and X11).
+package i3test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Message types of the i3 IPC protocol, see
+// https://i3wm.org/docs/ipc.html#_sending_messages_to_i3
+const (
+	RunCommand = 0
+	Subscribe  = 2
+	GetTree    = 4
+	GetMarks   = 5
+	GetVersion = 7
+)
+
+// eventTypes maps event names to their event reply types.
+var eventTypes = map[string]uint32{
+	"workspace":        0,
+	"output":           1,
+	"mode":             2,
+	"window":           3,
+	"barconfig_update": 4,
+	"binding":          5,
+	"shutdown":         6,
+	"tick":             7,
+	"bar_state_update": 20,
+}
+
+// Version is a GET_VERSION reply payload for handlers to return.
+const Version = `{"major": 4, "minor": 24, "patch": 0, "human_readable": "4.24"}`
+
+// Server is a fake i3. It uses little endian byte order.
+type Server struct {
+	// Path is the path of the UNIX socket on which the server listens.
+	Path string
+
+	handle func(t uint32, payload []byte) []byte
+	ln     net.Listener
+	dir    string
+
+	mu    sync.Mutex
+	conns map[*conn]bool
+}
+
+type conn struct {
+	net.Conn
+	mu     sync.Mutex // serializes writes
+	events map[string]bool
+}
+
+// NewServer starts a fake i3, which is stopped when the test finishes. handle
+// returns the reply payload for a request of message type t, or nil to not
+// reply at all, like i3 does for unknown message types. The server handles
+// SUBSCRIBE requests and the byte order detection itself.
+func NewServer(t testing.TB, handle func(t uint32, payload []byte) []byte) *Server {
+	t.Helper()
+	// UNIX socket paths are limited to 108 bytes, which t.TempDir might
+	// exceed.
+	dir, err := os.MkdirTemp("", "i3test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "ipc.sock")
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	s := &Server{
+		Path:   path,
+		handle: handle,
+		ln:     ln,
+		dir:    dir,
+		conns:  make(map[*conn]bool),
+	}
+	go s.serve()
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Close stops the server and closes all connections.
+func (s *Server) Close() {
+	s.ln.Close()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.conns {
+		c.Close()
+	}
+	os.RemoveAll(s.dir)
+}
+
+func (s *Server) serve() {
+	for {
+		nc, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		c := &conn{Conn: nc, events: make(map[string]bool)}
+		s.mu.Lock()
+		s.conns[c] = true
+		s.mu.Unlock()
+		go s.serveConn(c)
+	}
+}
+
+func (s *Server) serveConn(c *conn) {
+	defer func() {
+		c.Close()
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.mu.Unlock()
+	}()
+	for {
+		var h [14]byte
+		if _, err := io.ReadFull(c, h[:]); err != nil {
+			return
+		}
+		payload := make([]byte, binary.LittleEndian.Uint32(h[6:10]))
+		if _, err := io.ReadFull(c, payload); err != nil {
+			return
+		}
+		t := binary.LittleEndian.Uint32(h[10:14])
+		switch {
+		case t == Subscribe:
+			s.subscribe(c, payload)
+
+		case t == RunCommand && strings.HasPrefix(string(payload), "nop byte-order detection"):
+			c.write(t, []byte(`[{"success": true}]`))
+
+		case s.handle != nil:
+			if reply := s.handle(t, payload); reply != nil {
+				c.write(t, reply)
+			}
+		}
+	}
+}
+
+func (s *Server) subscribe(c *conn, payload []byte) {
+	var events []string
+	if err := json.Unmarshal(payload, &events); err != nil {
+		c.write(Subscribe, []byte(`{"success": false}`))
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, ev := range events {
+		c.events[ev] = true
+	}
+	c.writeLocked(Subscribe, []byte(`{"success": true}`))
+	if c.events["tick"] {
+		// Like i3, send a tick event right after subscribing.
+		c.writeLocked(1<<31|eventTypes["tick"], []byte(`{"first": true, "payload": ""}`))
+	}
+}
+
+// SendEvent sends an event with the specified name (e.g. "window") and
+// payload to all connections which subscribed to it, and returns their
+// number.
+func (s *Server) SendEvent(event, payload string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int
+	for c := range s.conns {
+		c.mu.Lock()
+		if c.events[event] {
+			c.writeLocked(1<<31|eventTypes[event], []byte(payload))
+			n++
+		}
+		c.mu.Unlock()
+	}
+	return n
+}
+
+func (c *conn) write(t uint32, payload []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.writeLocked(t, payload)
+}
+
+func (c *conn) writeLocked(t uint32, payload []byte) {
+	var buf bytes.Buffer
+	buf.WriteString("i3-ipc")
+	binary.Write(&buf, binary.LittleEndian, uint32(len(payload)))
+	binary.Write(&buf, binary.LittleEndian, t)
+	buf.Write(payload)
+	c.Write(buf.Bytes()) // errors surface in the client
+}
@@ -0,0 +1,40 @@
+// Package osutil contains operating system helpers which several packages of
+// this module share.
+package osutil
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// WriteFile atomically replaces the file at path with b, so that readers
+// never see a partially written file.
+func WriteFile(path string, b []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Start starts the program argv without waiting for it to exit. The process
+// is reaped in the background once it exits, after which exited receives the
+// result of cmd.Wait.
+func Start(argv []string) (cmd *exec.Cmd, exited <-chan error, _ error) {
+	cmd = exec.Command(argv[0], argv[1:]...)
+	if err := cmd.Start(); err != nil {
+		return nil, nil, err
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	return cmd, done, nil
+}
@@ -0,0 +1,48 @@
+package osutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+	for _, content := range []string{"first", "second"} {
+		if err := WriteFile(path, []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(b); got != content {
+			t.Errorf("after WriteFile(%q): got %q", content, got)
+		}
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("WriteFile left temporary files behind: %v", entries)
+	}
+}
+
+func TestStart(t *testing.T) {
+	t.Parallel()
+
+	cmd, exited, err := Start([]string{"sh", "-c", "exit 3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := <-exited; err == nil {
+		t.Errorf("exited: got nil, want exit status 3")
+	}
+	if !cmd.ProcessState.Exited() || cmd.ProcessState.ExitCode() != 3 {
+		t.Errorf("process not reaped: %v", cmd.ProcessState)
+	}
+}
@@ -0,0 +1,135 @@
+package i3
+
+import (
+	"context"
+	"fmt"
+
+	"go.i3wm.org/i3/v4/internal/osutil"
+)
+
+// windowPID is WindowPID, overridden in tests.
+var windowPID = WindowPID
+
+// LaunchAndWait starts the program argv and returns the container of its
+// window as soon as i3 manages it, so that follow-up commands can target the
+// window by con_id instead of guessing with sleep.
+//
+// The window is the first new window which matches c. If c is nil, the window
+// is identified by its _NET_WM_PID property (see WindowPID) instead, which
+// only works for X11 programs which set the property and do not fork. In that
+// case, LaunchAndWait returns an error if the program exits before its window
+// appears; with criteria, the program might be a launcher which exits after
+// starting the program which opens the window.
+//
+// LaunchAndWait subscribes to window events before starting argv, so the
+// window cannot be missed. Use ctx to specify a timeout. The program is
+// started directly (see ExecAndWait for starting it via i3) and keeps running
+// after LaunchAndWait returns.
+//
+// LaunchAndWait is supported in i3 ≥ v4.15 (2018-03-10).
+func LaunchAndWait(ctx context.Context, argv []string, c *Criteria) (*Node, error) {
+	if len(argv) == 0 {
+		return nil, fmt.Errorf("LaunchAndWait: empty argv")
+	}
+	return waitForWindow(ctx, c, func() (int, <-chan error, error) {
+		cmd, exited, err := osutil.Start(argv)
+		if err != nil {
+			return 0, nil, err
+		}
+		return cmd.Process.Pid, exited, nil
+	})
+}
+
+// ExecAndWait is like LaunchAndWait, but starts command via i3’s exec command,
+// so that the program runs in i3’s environment and with startup
+// notification. As the process ID of the program is unknown, c is required.
+//
+// ExecAndWait is supported in i3 ≥ v4.15 (2018-03-10).
+func ExecAndWait(ctx context.Context, command string, c *Criteria) (*Node, error) {
+	if c == nil {
+		return nil, fmt.Errorf("ExecAndWait: criteria required")
+	}
+	return waitForWindow(ctx, c, func() (int, <-chan error, error) {
+		_, err := RunCommand("exec " + Quote(command))
+		return 0, nil, err
+	})
+}
+
+// waitForWindow subscribes to window events, calls start and returns the
+// first new window which matches c or, if c is nil, whose process ID is pid.
+// If c is nil, exited (if non-nil) receives once the process exits.
+func waitForWindow(ctx context.Context, c *Criteria, start func() (pid int, exited <-chan error, _ error)) (*Node, error) {
+	// Subscribe to tick events as well: i3 sends a tick event right after
+	// subscribing, which tells us that no window event can be missed.
+	recv := Subscribe(WindowEventType, TickEventType)
+	defer recv.Close() // makes Next in the goroutine below return false
+	var (
+		windows = make(chan *Node)
+		ready   = make(chan struct{})
+		done    = make(chan struct{})
+	)
+	defer close(done)
+	go func() {
+		defer close(windows)
+		for recv.Next() {
+			switch ev := recv.Event().(type) {
+			case *TickEvent:
+				if ev.First {
+					close(ready)
+				}
+			case *WindowEvent:
+				if ev.Change != "new" {
+					continue
+				}
+				select {
+				case windows <- &ev.Container:
+				case <-done:
+					return
+				}
+			}
+		}
+	}()
+	select {
+	case <-ready:
+	case <-windows: // closed: the subscription failed
+		return nil, recv.Close()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	pid, exited, err := start()
+	if err != nil {
+		return nil, err
+	}
+	if c != nil {
+		exited = nil // e.g. launchers exit before the window appears
+	}
+
+	for {
+		select {
+		case n, ok := <-windows:
+			if !ok {
+				return nil, recv.Close()
+			}
+			if c != nil {
+				if c.Match(n) {
+					return n, nil
+				}
+				continue
+			}
+			if n.Window == 0 {
+				continue
+			}
+			if p, err := windowPID(n.Window); err == nil && p == pid {
+				return n, nil
+			}
+		case err := <-exited:
+			if err == nil {
+				return nil, fmt.Errorf("process %d exited before its window appeared", pid)
+			}
+			return nil, fmt.Errorf("process %d exited before its window appeared: %w", pid, err)
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
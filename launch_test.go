@@ -0,0 +1,160 @@
+package i3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"go.i3wm.org/i3/v4/internal/i3test"
+)
+
+func TestLaunchAndWait(t *testing.T) {
+	// Not parallel: uses a fake i3 and modifies windowPID.
+	commands := make(chan string, 1)
+	srv := useFakeI3(t, func(typ uint32, payload []byte) []byte {
+		switch typ {
+		case i3test.GetVersion:
+			return []byte(i3test.Version)
+		case i3test.RunCommand:
+			commands <- string(payload)
+			return []byte(`[{"success": true}]`)
+		}
+		return nil
+	})
+
+	var (
+		pidsMu sync.Mutex
+		pids   = make(map[int64]int) // by X11 window ID
+	)
+	origWindowPID := windowPID
+	defer func() { windowPID = origWindowPID }()
+	windowPID = func(window int64) (int, error) {
+		pidsMu.Lock()
+		defer pidsMu.Unlock()
+		return pids[window], nil
+	}
+
+	// newWindow sends a window event, which must happen only once the
+	// subscription is known to be established.
+	newWindow := func(t *testing.T, con NodeID, window int64, class string) {
+		t.Helper()
+		payload := fmt.Sprintf(`{"change": "new", "container": {"id": %d, "window": %d, "window_properties": {"class": %q}}}`, con, window, class)
+		if srv.SendEvent("window", payload) == 0 {
+			t.Fatalf("no subscription for window events")
+		}
+	}
+
+	// waitForFile returns the contents of path once the program under test
+	// wrote it, which it does after the subscription was established.
+	waitForFile := func(t *testing.T, path string) string {
+		t.Helper()
+		for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(5 * time.Millisecond) {
+			if b, err := os.ReadFile(path); err == nil && strings.HasSuffix(string(b), "\n") {
+				return strings.TrimSpace(string(b))
+			}
+		}
+		t.Fatalf("timeout waiting for %s", path)
+		return ""
+	}
+
+	type result struct {
+		n   *Node
+		err error
+	}
+	async := func(fn func() (*Node, error)) <-chan result {
+		res := make(chan result, 1)
+		go func() {
+			n, err := fn()
+			res <- result{n, err}
+		}()
+		return res
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	t.Run("PID", func(t *testing.T) {
+		pidFile := filepath.Join(t.TempDir(), "pid")
+		res := async(func() (*Node, error) {
+			return LaunchAndWait(ctx, []string{"sh", "-c", `echo $$ > "$0"; exec sleep 10`, pidFile}, nil)
+		})
+		pid, err := strconv.Atoi(waitForFile(t, pidFile))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer syscall.Kill(pid, syscall.SIGKILL)
+		pidsMu.Lock()
+		pids[1001] = pid + 1
+		pids[1002] = pid
+		pidsMu.Unlock()
+		newWindow(t, 21, 1001, "Other")
+		newWindow(t, 22, 1002, "Launched")
+		if r := <-res; r.err != nil || r.n.ID != 22 {
+			t.Errorf("LaunchAndWait = %v, %v, want container 22", r.n, r.err)
+		}
+	})
+
+	t.Run("Criteria", func(t *testing.T) {
+		marker := filepath.Join(t.TempDir(), "marker")
+		res := async(func() (*Node, error) {
+			// The program exits right away, like a launcher would.
+			return LaunchAndWait(ctx, []string{"sh", "-c", `echo > "$0"`, marker}, MustParseCriteria(`[class="^Launched$"]`))
+		})
+		waitForFile(t, marker)
+		newWindow(t, 21, 1001, "Other")
+		newWindow(t, 22, 1002, "Launched")
+		if r := <-res; r.err != nil || r.n.ID != 22 {
+			t.Errorf("LaunchAndWait = %v, %v, want container 22", r.n, r.err)
+		}
+	})
+
+	t.Run("Exec", func(t *testing.T) {
+		res := async(func() (*Node, error) {
+			return ExecAndWait(ctx, `xterm -title "vim"`, MustParseCriteria(`[class="^XTerm$"]`))
+		})
+		if got, want := <-commands, `exec "xterm -title \"vim\""`; got != want {
+			t.Errorf("ExecAndWait sent command %q, want %q", got, want)
+		}
+		newWindow(t, 21, 1001, "Other")
+		newWindow(t, 22, 1002, "XTerm")
+		if r := <-res; r.err != nil || r.n.ID != 22 {
+			t.Errorf("ExecAndWait = %v, %v, want container 22", r.n, r.err)
+		}
+
+		if _, err := ExecAndWait(ctx, "xterm", nil); err == nil {
+			t.Errorf("ExecAndWait without criteria unexpectedly succeeded")
+		}
+	})
+
+	t.Run("Exited", func(t *testing.T) {
+		_, err := LaunchAndWait(ctx, []string{"sh", "-c", "exit 3"}, nil)
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) || exitErr.ExitCode() != 3 {
+			t.Errorf("LaunchAndWait: got %v, want *exec.ExitError with exit status 3", err)
+		}
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		_, err := LaunchAndWait(ctx, []string{"true"}, MustParseCriteria(`[class="^Never$"]`))
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("LaunchAndWait: got %v, want context.DeadlineExceeded", err)
+		}
+	})
+
+	t.Run("EmptyArgv", func(t *testing.T) {
+		if _, err := LaunchAndWait(ctx, nil, nil); err == nil {
+			t.Errorf("LaunchAndWait with empty argv unexpectedly succeeded")
+		}
+	})
+}
@@ -0,0 +1,331 @@
+// Package layout saves the layout of workspaces and restores it later via
+// i3’s append_layout command, e.g. to recreate standard workspaces after
+// logging in again.
+//
+// See https://i3wm.org/docs/layout-saving.html for details on how i3 restores
+// layouts: placeholder containers are created, which swallow the next window
+// matching their criteria.
+package layout
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"go.i3wm.org/i3/v4"
+	"go.i3wm.org/i3/v4/internal/daemon"
+	"go.i3wm.org/i3/v4/internal/osutil"
+)
+
+// Swallow contains the criteria of a placeholder container, in the same
+// format as i3-save-tree(1) outputs them: anchored regular expressions.
+type Swallow struct {
+	Class      string `json:"class,omitempty"`
+	Instance   string `json:"instance,omitempty"`
+	WindowRole string `json:"window_role,omitempty"`
+}
+
+// Container is a container in the JSON format of i3’s append_layout command.
+type Container struct {
+	Type     i3.NodeType     `json:"type,omitempty"`
+	Layout   i3.Layout       `json:"layout,omitempty"`
+	Percent  float64         `json:"percent,omitempty"`
+	Border   i3.BorderStyle  `json:"border,omitempty"`
+	Floating i3.FloatingType `json:"floating,omitempty"`
+	Rect     *i3.Rect        `json:"rect,omitempty"` // floating containers only
+	Marks    []string        `json:"marks,omitempty"`
+	Swallows []Swallow       `json:"swallows,omitempty"` // windows only
+	Nodes    []Container     `json:"nodes,omitempty"`
+}
+
+// Workspace is the saved layout of a workspace.
+type Workspace struct {
+	Name          string      `json:"name"`
+	Layout        i3.Layout   `json:"layout"`
+	Nodes         []Container `json:"nodes,omitempty"`
+	FloatingNodes []Container `json:"floating_nodes,omitempty"`
+}
+
+// Snapshot returns the layout of workspace ws (a workspace node, e.g. from
+// the layout tree). The boolean result is false if ws contains no windows or
+// still contains placeholder containers, in which case the layout is not
+// worth saving.
+func Snapshot(ws *i3.Node) (Workspace, bool) {
+	complete, windows := true, 0
+	var convert func(n *i3.Node) Container
+	convert = func(n *i3.Node) Container {
+		c := Container{
+			Type:    n.Type,
+			Percent: n.Percent,
+			Border:  n.Border,
+			Marks:   n.Marks,
+		}
+		if n.Type == i3.FloatingCon {
+			r := n.Rect
+			c.Rect = &r
+			c.Floating = n.Floating
+		}
+		switch {
+		case n.Window != 0:
+			windows++
+			c.Swallows = []Swallow{swallow(n.WindowProperties)}
+		case len(n.Nodes) == 0 && n.Type != i3.FloatingCon:
+			complete = false // placeholder container
+		default:
+			c.Layout = n.Layout
+		}
+		for _, child := range n.Nodes {
+			c.Nodes = append(c.Nodes, convert(child))
+		}
+		return c
+	}
+	w := Workspace{Name: ws.Name, Layout: ws.Layout}
+	for _, n := range ws.Nodes {
+		w.Nodes = append(w.Nodes, convert(n))
+	}
+	for _, n := range ws.FloatingNodes {
+		w.FloatingNodes = append(w.FloatingNodes, convert(n))
+	}
+	return w, complete && windows > 0
+}
+
+func anchored(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "^" + regexp.QuoteMeta(s) + "$"
+}
+
+func swallow(p i3.WindowProperties) Swallow {
+	return Swallow{
+		Class:      anchored(p.Class),
+		Instance:   anchored(p.Instance),
+		WindowRole: anchored(p.Role),
+	}
+}
+
+// AppendLayout returns the contents of a file for i3’s append_layout
+// command which recreates w on the focused workspace. As append_layout cannot
+// change the layout of the workspace itself, multiple tiling containers are
+// wrapped in a container with w’s layout.
+func (w Workspace) AppendLayout() ([]byte, error) {
+	cons := w.Nodes
+	if len(cons) > 1 {
+		cons = []Container{{Type: i3.Con, Layout: w.Layout, Nodes: cons}}
+	}
+	cons = append(cons, w.FloatingNodes...)
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", "    ")
+	for _, c := range cons {
+		// append_layout expects one JSON object per top-level container.
+		if err := enc.Encode(c); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// Swallows returns the criteria of all placeholder containers in w.
+func (w Workspace) Swallows() []Swallow {
+	var swallows []Swallow
+	var walk func(cons []Container)
+	walk = func(cons []Container) {
+		for _, c := range cons {
+			swallows = append(swallows, c.Swallows...)
+			walk(c.Nodes)
+		}
+	}
+	walk(w.Nodes)
+	walk(w.FloatingNodes)
+	return swallows
+}
+
+// Service saves workspace layouts whenever they change and restores them.
+type Service struct {
+	// Dir is the directory in which layouts are saved, one file per
+	// workspace.
+	Dir string
+
+	// Launch maps window classes (as in i3.WindowProperties) to the
+	// command line of the application which opens such windows. When
+	// restoring a layout, the application is started once for each
+	// placeholder container with that class.
+	Launch map[string][]string
+
+	// AutoRestore, if true, restores the saved layout of a workspace (if
+	// any) when the user switches to the workspace after i3 created it.
+	AutoRestore bool
+
+	saved   map[string][]byte // workspace name → last saved file contents
+	pending map[string]bool   // created workspaces to restore once focused
+}
+
+func (s *Service) path(workspace string) string {
+	return filepath.Join(s.Dir, url.PathEscape(workspace)+".json")
+}
+
+// Load returns the saved layout of the specified workspace.
+func (s *Service) Load(workspace string) (Workspace, error) {
+	var w Workspace
+	b, err := os.ReadFile(s.path(workspace))
+	if err != nil {
+		return w, err
+	}
+	if err := json.Unmarshal(b, &w); err != nil {
+		return w, fmt.Errorf("%s: %v", s.path(workspace), err)
+	}
+	return w, nil
+}
+
+// Save saves the layouts of all workspaces in tree which changed since the
+// last call to Save.
+func (s *Service) Save(tree i3.Tree) error {
+	if s.saved == nil {
+		s.saved = make(map[string][]byte)
+	}
+	var workspaces []*i3.Node
+	tree.Root.FindChild(func(n *i3.Node) bool {
+		if n.Type == i3.WorkspaceNode && n.Name != "__i3_scratch" {
+			workspaces = append(workspaces, n)
+		}
+		return false // visit all nodes
+	})
+	for _, ws := range workspaces {
+		w, ok := Snapshot(ws)
+		if !ok {
+			continue
+		}
+		b, err := json.MarshalIndent(w, "", "    ")
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(s.saved[w.Name], b) {
+			continue
+		}
+		if err := osutil.WriteFile(s.path(w.Name), b); err != nil {
+			return err
+		}
+		s.saved[w.Name] = b
+	}
+	return nil
+}
+
+// Restore switches to the specified workspace (unless it is focused already), creates placeholder
+// containers for its saved layout and starts the applications (see Launch)
+// whose windows the placeholders swallow.
+func (s *Service) Restore(workspace string) error {
+	w, err := s.Load(workspace)
+	if err != nil {
+		return err
+	}
+	b, err := w.AppendLayout()
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp("", "i3-layout")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	// i3 reads the file before replying, so it can be removed afterwards.
+	if _, err := i3.RunCommand(fmt.Sprintf("workspace --no-auto-back-and-forth %s; append_layout %s",
+		i3.Quote(workspace), i3.Quote(f.Name()))); err != nil {
+		return err
+	}
+	for _, sw := range w.Swallows() {
+		for class, argv := range s.Launch {
+			if len(argv) == 0 || sw.Class != anchored(class) {
+				continue
+			}
+			if _, _, err := osutil.Start(argv); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Handle saves or restores layouts in response to ev. Run calls Handle, so
+// only call Handle if you read events yourself.
+func (s *Service) Handle(ev i3.Event) error {
+	switch ev := ev.(type) {
+	case *i3.WorkspaceEvent:
+		switch ev.Change {
+		case "init":
+			// i3 also creates workspaces without switching to them, e.g.
+			// for “move container to workspace”, so restoring right away
+			// would steal the focus.
+			if s.AutoRestore {
+				if s.pending == nil {
+					s.pending = make(map[string]bool)
+				}
+				s.pending[ev.Current.Name] = true
+			}
+			return nil
+
+		case "empty":
+			delete(s.pending, ev.Current.Name)
+			return nil
+
+		case "focus":
+			if !s.pending[ev.Current.Name] {
+				return nil
+			}
+			delete(s.pending, ev.Current.Name)
+			if len(ev.Current.Nodes) > 0 || len(ev.Current.FloatingNodes) > 0 {
+				return nil // e.g. a window was moved to the workspace
+			}
+			if _, err := os.Stat(s.path(ev.Current.Name)); err != nil {
+				return nil // no saved layout
+			}
+			if err := s.Restore(ev.Current.Name); err != nil && !i3.IsUnsuccessful(err) {
+				return err
+			}
+			return nil
+
+		case "rename", "move":
+		default:
+			return nil
+		}
+
+	case *i3.WindowEvent:
+		switch ev.Change {
+		case "new", "close", "move", "floating":
+		default:
+			return nil
+		}
+
+	case *i3.BindingEvent:
+		// Commands such as layout, split or resize change the layout
+		// without window events.
+
+	default:
+		return nil
+	}
+	tree, err := i3.GetTree()
+	if err != nil {
+		return err
+	}
+	return s.Save(tree)
+}
+
+// Run saves layouts whenever windows are opened, closed or moved, workspaces
+// are renamed or moved, or key bindings are run, and, if AutoRestore is true,
+// restores layouts of newly created workspaces, until i3 exits.
+//
+// Run is supported in i3 ≥ v4.14 (2017-09-04).
+func (s *Service) Run() error {
+	return daemon.Run(s.Handle, i3.WindowEventType, i3.WorkspaceEventType, i3.BindingEventType)
+}
@@ -0,0 +1,256 @@
+package layout
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.i3wm.org/i3/v4"
+	"go.i3wm.org/i3/v4/internal/i3test"
+)
+
+func testWorkspace() *i3.Node {
+	return &i3.Node{
+		ID:     4,
+		Name:   "1: dev",
+		Type:   i3.WorkspaceNode,
+		Layout: i3.SplitH,
+		Nodes: []*i3.Node{
+			{
+				ID:               10,
+				Type:             i3.Con,
+				Layout:           i3.SplitH,
+				Percent:          0.5,
+				Border:           i3.NormalBorder,
+				Window:           1000,
+				WindowProperties: i3.WindowProperties{Class: "Emacs", Instance: "emacs"},
+			},
+			{
+				ID:      11,
+				Type:    i3.Con,
+				Layout:  i3.SplitV,
+				Percent: 0.5,
+				Border:  i3.NormalBorder,
+				Nodes: []*i3.Node{
+					{
+						ID:               12,
+						Type:             i3.Con,
+						Percent:          1,
+						Border:           i3.PixelBorder,
+						Window:           1001,
+						Marks:            []string{"term"},
+						WindowProperties: i3.WindowProperties{Class: "URxvt", Instance: "urxvt", Role: "a.b"},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	t.Parallel()
+
+	got, ok := Snapshot(testWorkspace())
+	if !ok {
+		t.Fatalf("Snapshot: unexpectedly incomplete")
+	}
+	want := Workspace{
+		Name:   "1: dev",
+		Layout: i3.SplitH,
+		Nodes: []Container{
+			{
+				Type:     i3.Con,
+				Percent:  0.5,
+				Border:   i3.NormalBorder,
+				Swallows: []Swallow{{Class: "^Emacs$", Instance: "^emacs$"}},
+			},
+			{
+				Type:    i3.Con,
+				Layout:  i3.SplitV,
+				Percent: 0.5,
+				Border:  i3.NormalBorder,
+				Nodes: []Container{
+					{
+						Type:     i3.Con,
+						Percent:  1,
+						Border:   i3.PixelBorder,
+						Marks:    []string{"term"},
+						Swallows: []Swallow{{Class: "^URxvt$", Instance: "^urxvt$", WindowRole: `^a\.b$`}},
+					},
+				},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Snapshot: unexpected result: (-want +got)\n%s", diff)
+	}
+
+	if diff := cmp.Diff(
+		[]Swallow{want.Nodes[0].Swallows[0], want.Nodes[1].Nodes[0].Swallows[0]},
+		got.Swallows()); diff != "" {
+		t.Errorf("Swallows: unexpected result: (-want +got)\n%s", diff)
+	}
+
+	// Placeholder containers (from restoring a layout) are not saved.
+	ws := testWorkspace()
+	ws.Nodes[0].Window = 0
+	if _, ok := Snapshot(ws); ok {
+		t.Errorf("Snapshot: placeholder container not detected")
+	}
+	if _, ok := Snapshot(&i3.Node{Type: i3.WorkspaceNode, Name: "empty"}); ok {
+		t.Errorf("Snapshot: empty workspace not detected")
+	}
+}
+
+func TestAppendLayout(t *testing.T) {
+	t.Parallel()
+
+	w := Workspace{
+		Layout: i3.Tabbed,
+		Nodes: []Container{
+			{Type: i3.Con, Swallows: []Swallow{{Class: "^A$"}}},
+			{Type: i3.Con, Swallows: []Swallow{{Class: "^B$"}}},
+		},
+		FloatingNodes: []Container{
+			{
+				Type:     i3.FloatingCon,
+				Floating: i3.UserOn,
+				Rect:     &i3.Rect{X: 10, Y: 20, Width: 300, Height: 200},
+				Nodes:    []Container{{Type: i3.Con, Swallows: []Swallow{{Class: "^C$"}}}},
+			},
+		},
+	}
+	b, err := w.AppendLayout()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{
+    "type": "con",
+    "layout": "tabbed",
+    "nodes": [
+        {
+            "type": "con",
+            "swallows": [
+                {
+                    "class": "^A$"
+                }
+            ]
+        },
+        {
+            "type": "con",
+            "swallows": [
+                {
+                    "class": "^B$"
+                }
+            ]
+        }
+    ]
+}
+{
+    "type": "floating_con",
+    "floating": "user_on",
+    "rect": {
+        "x": 10,
+        "y": 20,
+        "width": 300,
+        "height": 200
+    },
+    "nodes": [
+        {
+            "type": "con",
+            "swallows": [
+                {
+                    "class": "^C$"
+                }
+            ]
+        }
+    ]
+}
+`
+	if diff := cmp.Diff(want, string(b)); diff != "" {
+		t.Errorf("AppendLayout: unexpected result: (-want +got)\n%s", diff)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	t.Parallel()
+
+	s := &Service{Dir: t.TempDir()}
+	tree := i3.Tree{Root: &i3.Node{
+		Type: i3.Root,
+		Nodes: []*i3.Node{{
+			Type:  i3.OutputNode,
+			Nodes: []*i3.Node{{Type: i3.Con, Nodes: []*i3.Node{testWorkspace()}}},
+		}},
+	}}
+	if err := s.Save(tree); err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.Load("1: dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := Snapshot(testWorkspace())
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Load: unexpected result: (-want +got)\n%s", diff)
+	}
+}
+
+func TestHandle(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		commands []string
+	)
+	srv := i3test.NewServer(t, func(typ uint32, payload []byte) []byte {
+		switch typ {
+		case i3test.GetVersion:
+			return []byte(i3test.Version)
+		case i3test.GetTree:
+			return []byte(`{"id": 1, "type": "root", "nodes": []}`)
+		case i3test.RunCommand:
+			mu.Lock()
+			defer mu.Unlock()
+			commands = append(commands, string(payload))
+			return []byte(`[{"success": true}]`)
+		}
+		return nil
+	})
+	i3.SocketPathHook = func() (string, error) { return srv.Path, nil }
+	t.Cleanup(func() { i3.SocketPathHook = nil })
+
+	s := &Service{Dir: t.TempDir(), AutoRestore: true}
+	tree := i3.Tree{Root: &i3.Node{
+		Type: i3.Root,
+		Nodes: []*i3.Node{{
+			Type:  i3.OutputNode,
+			Nodes: []*i3.Node{{Type: i3.Con, Nodes: []*i3.Node{testWorkspace()}}},
+		}},
+	}}
+	if err := s.Save(tree); err != nil {
+		t.Fatal(err)
+	}
+	workspace := func(change, name string) *i3.WorkspaceEvent {
+		return &i3.WorkspaceEvent{
+			Change:  change,
+			Current: i3.Node{Name: name, Type: i3.WorkspaceNode},
+		}
+	}
+	for _, ev := range []i3.Event{
+		workspace("init", "1: dev"), // e.g. move container to workspace
+		workspace("focus", "2"),     // not created
+		workspace("init", "3"),      // no saved layout
+		workspace("focus", "3"),
+		workspace("focus", "1: dev"), // restores
+		workspace("focus", "1: dev"), // already restored
+	} {
+		if err := s.Handle(ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(commands) != 1 || !strings.HasPrefix(commands[0], `workspace --no-auto-back-and-forth "1: dev"; append_layout `) {
+		t.Errorf("Handle: got commands %q, want a single append_layout for workspace 1: dev", commands)
+	}
+}
@@ -0,0 +1,25 @@
+package i3
+
+import "log/slog"
+
+// Logger receives diagnostics of this package: connects and reconnect
+// attempts, the detected byte order, version mismatches and events dropped
+// while re-subscribing. Messages use the following levels:
+//
+//   - Debug: connection handling, e.g. reconnect attempts, version changes
+//     and events dropped while re-subscribing
+//   - Info: the workaround for old i3 versions in Restart
+//   - Warn: unsupported i3 variants
+//
+// If Logger is nil, slog.Default() is used, which writes Info and above to
+// the standard log package. To silence the package, use e.g.
+// slog.New(slog.NewTextHandler(io.Discard, nil)). Set Logger before sending
+// the first request; changing it concurrently is not safe.
+var Logger *slog.Logger
+
+func logger() *slog.Logger {
+	if Logger != nil {
+		return Logger
+	}
+	return slog.Default()
+}
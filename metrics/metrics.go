@@ -0,0 +1,194 @@
+// Package metrics collects metrics about the communication with i3 via
+// i3.IPCHooks and exposes them in the Prometheus text format, e.g. to
+// monitor long-running i3 daemons:
+//
+//	c := metrics.New()
+//	i3.IPCHooks = c
+//	go http.ListenAndServe("localhost:9143", c)
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.i3wm.org/i3/v4"
+)
+
+// durationBuckets are the upper bounds (in seconds) of the request duration
+// histogram buckets. Most requests take well below a millisecond, whereas
+// GET_TREE of large layouts and requests during i3 restarts take longer.
+var durationBuckets = []float64{0.0001, 0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5}
+
+type requestStats struct {
+	count        uint64
+	errors       uint64
+	requestBytes uint64
+	replyBytes   uint64
+	buckets      []uint64 // per durationBuckets, not cumulative
+	sum          float64  // seconds
+}
+
+type eventStats struct {
+	count uint64
+	bytes uint64
+}
+
+// Collector implements i3.Hooks and http.Handler.
+//
+// Collector is safe for concurrent use.
+type Collector struct {
+	mu         sync.Mutex
+	requests   map[string]*requestStats // by message type name
+	events     map[i3.EventType]*eventStats
+	reconnects uint64
+	inFlight   int
+	waiting    int
+}
+
+// New returns a Collector. Install it by setting i3.IPCHooks.
+func New() *Collector {
+	return &Collector{
+		requests: make(map[string]*requestStats),
+		events:   make(map[i3.EventType]*eventStats),
+	}
+}
+
+// RoundTripStart implements i3.Hooks.
+func (c *Collector) RoundTripStart(rt *i3.RoundTrip) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.inFlight++
+}
+
+// RoundTripEnd implements i3.Hooks.
+func (c *Collector) RoundTripEnd(rt *i3.RoundTrip) {
+	d := time.Since(rt.Start).Seconds()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.inFlight--
+	s, ok := c.requests[rt.Name]
+	if !ok {
+		s = &requestStats{buckets: make([]uint64, len(durationBuckets))}
+		c.requests[rt.Name] = s
+	}
+	s.count++
+	if rt.Err != nil {
+		s.errors++
+	}
+	s.requestBytes += uint64(rt.RequestSize)
+	s.replyBytes += uint64(rt.ReplySize)
+	s.sum += d
+	if i := sort.SearchFloat64s(durationBuckets, d); i < len(durationBuckets) {
+		s.buckets[i]++
+	}
+}
+
+// PoolWaiting implements i3.Hooks.
+func (c *Collector) PoolWaiting(waiting int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.waiting = waiting
+}
+
+// EventReceived implements i3.Hooks.
+func (c *Collector) EventReceived(t i3.EventType, payloadSize int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.events[t]
+	if !ok {
+		s = &eventStats{}
+		c.events[t] = s
+	}
+	s.count++
+	s.bytes += uint64(payloadSize)
+}
+
+// Reconnect implements i3.Hooks.
+func (c *Collector) Reconnect(attempt int, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reconnects++
+}
+
+func sortedKeys[K ~string, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// ServeHTTP writes all metrics in the Prometheus text exposition format.
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	bw := bufio.NewWriter(w)
+	c.writeTo(bw)
+	bw.Flush()
+}
+
+func (c *Collector) writeTo(w *bufio.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	header := func(name, typ, help string) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+	}
+	types := sortedKeys(c.requests)
+	perType := func(name, typ, help string, value func(s *requestStats) uint64) {
+		header(name, typ, help)
+		for _, t := range types {
+			fmt.Fprintf(w, "%s{type=%q} %d\n", name, t, value(c.requests[t]))
+		}
+	}
+
+	perType("i3_requests_total", "counter", "Requests sent to i3, by message type.",
+		func(s *requestStats) uint64 { return s.count })
+	perType("i3_request_errors_total", "counter", "Requests which failed, by message type.",
+		func(s *requestStats) uint64 { return s.errors })
+	perType("i3_request_payload_bytes_total", "counter", "Payload bytes sent to i3, by message type.",
+		func(s *requestStats) uint64 { return s.requestBytes })
+	perType("i3_reply_payload_bytes_total", "counter", "Payload bytes received from i3 in replies, by message type.",
+		func(s *requestStats) uint64 { return s.replyBytes })
+
+	const duration = "i3_request_duration_seconds"
+	header(duration, "histogram", "Request latency, including waiting for a connection and reconnects, by message type.")
+	for _, t := range types {
+		s := c.requests[t]
+		var cumulative uint64
+		for i, le := range durationBuckets {
+			cumulative += s.buckets[i]
+			fmt.Fprintf(w, "%s_bucket{type=%q,le=%q} %d\n", duration, t, formatFloat(le), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket{type=%q,le=\"+Inf\"} %d\n", duration, t, s.count)
+		fmt.Fprintf(w, "%s_sum{type=%q} %s\n", duration, t, formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count{type=%q} %d\n", duration, t, s.count)
+	}
+
+	header("i3_requests_in_flight", "gauge", "Requests currently waiting for a reply or a connection.")
+	fmt.Fprintf(w, "i3_requests_in_flight %d\n", c.inFlight)
+	header("i3_requests_waiting", "gauge", "Requests currently waiting for a pooled connection.")
+	fmt.Fprintf(w, "i3_requests_waiting %d\n", c.waiting)
+
+	header("i3_reconnects_total", "counter", "Attempts to re-establish a connection to i3.")
+	fmt.Fprintf(w, "i3_reconnects_total %d\n", c.reconnects)
+
+	events := sortedKeys(c.events)
+	header("i3_events_total", "counter", "Events received from i3, by event type.")
+	for _, t := range events {
+		fmt.Fprintf(w, "i3_events_total{type=%q} %d\n", t, c.events[t].count)
+	}
+	header("i3_event_payload_bytes_total", "counter", "Payload bytes received from i3 in events, by event type.")
+	for _, t := range events {
+		fmt.Fprintf(w, "i3_event_payload_bytes_total{type=%q} %d\n", t, c.events[t].bytes)
+	}
+}
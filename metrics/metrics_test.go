@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.i3wm.org/i3/v4"
+)
+
+var _ i3.Hooks = (*Collector)(nil)
+
+func TestCollector(t *testing.T) {
+	t.Parallel()
+
+	c := New()
+	for _, rt := range []*i3.RoundTrip{
+		{Name: "get_tree", RequestSize: 0, ReplySize: 1000},
+		{Name: "get_tree", RequestSize: 0, ReplySize: 500},
+		{Name: "run_command", RequestSize: 10, Err: errors.New("broken pipe")},
+	} {
+		reply, err := rt.ReplySize, rt.Err
+		rt.ReplySize, rt.Err = 0, nil
+		rt.Start = time.Now().Add(-2 * time.Millisecond)
+		c.RoundTripStart(rt)
+		rt.ReplySize, rt.Err = reply, err
+		c.RoundTripEnd(rt)
+	}
+	c.RoundTripStart(&i3.RoundTrip{Name: "get_marks"})
+	c.PoolWaiting(1)
+	c.PoolWaiting(2)
+	c.EventReceived(i3.WindowEventType, 300)
+	c.EventReceived(i3.WindowEventType, 200)
+	c.EventReceived("69", 10)
+	c.Reconnect(1, errors.New("EOF"))
+
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if got, want := rec.Header().Get("Content-Type"), "text/plain; version=0.0.4; charset=utf-8"; got != want {
+		t.Errorf("Content-Type = %q, want %q", got, want)
+	}
+	got := rec.Body.String()
+	for _, want := range []string{
+		`i3_requests_total{type="get_tree"} 2`,
+		`i3_requests_total{type="run_command"} 1`,
+		`i3_request_errors_total{type="get_tree"} 0`,
+		`i3_request_errors_total{type="run_command"} 1`,
+		`i3_request_payload_bytes_total{type="run_command"} 10`,
+		`i3_reply_payload_bytes_total{type="get_tree"} 1500`,
+		`i3_request_duration_seconds_bucket{type="get_tree",le="0.001"} 0`,
+		`i3_request_duration_seconds_bucket{type="get_tree",le="1"} 2`,
+		`i3_request_duration_seconds_bucket{type="get_tree",le="+Inf"} 2`,
+		`i3_request_duration_seconds_count{type="get_tree"} 2`,
+		`i3_requests_in_flight 1`,
+		`i3_requests_waiting 2`,
+		`i3_reconnects_total 1`,
+		`i3_events_total{type="69"} 1`,
+		`i3_events_total{type="window"} 2`,
+		`i3_event_payload_bytes_total{type="window"} 500`,
+	} {
+		if !strings.Contains(got, want+"\n") {
+			t.Errorf("metrics do not contain %q", want)
+		}
+	}
+	if t.Failed() {
+		t.Logf("metrics:\n%s", got)
+	}
+}
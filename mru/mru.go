@@ -0,0 +1,224 @@
+// Package mru tracks the most recently used (i.e. focused) windows, which i3
+// does not expose globally, to implement alt-tab style window switching.
+package mru
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"go.i3wm.org/i3/v4"
+	"go.i3wm.org/i3/v4/internal/daemon"
+	"go.i3wm.org/i3/v4/internal/osutil"
+)
+
+// entry identifies a window. Container IDs change when i3 restarts, whereas
+// X11 window IDs do not, so both are tracked.
+type entry struct {
+	Con    i3.NodeID `json:"con"`
+	Window int64     `json:"window"` // 0 for Wayland windows
+}
+
+// Tracker maintains the list of windows in most recently focused order.
+//
+// Tracker is safe for concurrent use.
+type Tracker struct {
+	statePath string
+	saveMu    sync.Mutex // serializes writes to statePath, see save
+
+	mu      sync.Mutex
+	entries []entry // most recently focused first
+	remap   bool    // container IDs are stale, see remapIDs
+}
+
+// New returns a Tracker. If statePath is non-empty, the history is persisted
+// in that file, so that it survives restarts of the Tracker’s process. The
+// history survives in-place restarts of i3 in either case.
+func New(statePath string) (*Tracker, error) {
+	t := &Tracker{statePath: statePath}
+	if statePath == "" {
+		return t, nil
+	}
+	b, err := os.ReadFile(statePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return t, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &t.entries); err != nil {
+		return nil, fmt.Errorf("%s: %v", statePath, err)
+	}
+	// i3 might have been restarted since the file was written.
+	t.remap = true
+	return t, nil
+}
+
+// Run updates the history in response to window events until i3 exits (in
+// which case Run returns nil) or the subscription fails. Typically, Run is
+// called in a separate goroutine.
+//
+// Run is supported in i3 ≥ v4.14 (2017-09-04).
+func (t *Tracker) Run() error {
+	return daemon.Run(t.Handle, i3.WindowEventType)
+}
+
+// Handle updates the history in response to ev. Run calls Handle, so only
+// call Handle if you read events yourself.
+func (t *Tracker) Handle(ev i3.Event) error {
+	switch ev := ev.(type) {
+	case *i3.ShutdownEvent:
+		if ev.Change == "restart" {
+			t.mu.Lock()
+			t.remap = true
+			t.mu.Unlock()
+		}
+		return nil
+
+	case *i3.WindowEvent:
+		if ev.Change != "focus" && ev.Change != "close" {
+			return nil
+		}
+		if err := t.remapIDs(); err != nil {
+			return err
+		}
+		t.mu.Lock()
+		t.removeLocked(ev.Container.ID)
+		if ev.Change == "focus" {
+			t.entries = append([]entry{{Con: ev.Container.ID, Window: ev.Container.Window}}, t.entries...)
+		}
+		t.mu.Unlock()
+		return t.save()
+	}
+	return nil
+}
+
+func (t *Tracker) removeLocked(id i3.NodeID) {
+	for i, e := range t.entries {
+		if e.Con == id {
+			t.entries = append(t.entries[:i], t.entries[i+1:]...)
+			return
+		}
+	}
+}
+
+// remapIDs updates the container IDs after i3 restarted, based on X11
+// window IDs, and forgets windows which no longer exist.
+func (t *Tracker) remapIDs() error {
+	t.mu.Lock()
+	remap := t.remap
+	t.mu.Unlock()
+	if !remap {
+		return nil
+	}
+	tree, err := i3.GetTree()
+	if err != nil {
+		return err
+	}
+	cons := make(map[int64]i3.NodeID)
+	tree.Root.FindChild(func(n *i3.Node) bool {
+		if n.Window != 0 {
+			cons[n.Window] = n.ID
+		}
+		return false // visit all nodes
+	})
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	remapped := t.entries[:0]
+	for _, e := range t.entries {
+		if con, ok := cons[e.Window]; ok && e.Window != 0 {
+			e.Con = con
+			remapped = append(remapped, e)
+		}
+	}
+	t.entries = remapped
+	t.remap = false
+	return nil
+}
+
+// save writes the history to statePath without holding mu during the write.
+// As saveMu serializes the writes, the file ends up with the latest history.
+func (t *Tracker) save() error {
+	if t.statePath == "" {
+		return nil
+	}
+	t.saveMu.Lock()
+	defer t.saveMu.Unlock()
+	t.mu.Lock()
+	b, err := json.Marshal(t.entries)
+	t.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return osutil.WriteFile(t.statePath, b)
+}
+
+// Global returns the container IDs of all windows, most recently focused
+// first.
+func (t *Tracker) Global() []i3.NodeID {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ids := make([]i3.NodeID, len(t.entries))
+	for i, e := range t.entries {
+		ids[i] = e.Con
+	}
+	return ids
+}
+
+// Workspace returns the container IDs of the windows on workspace ws (a
+// workspace node, e.g. from the layout tree), most recently focused first.
+func (t *Tracker) Workspace(ws *i3.Node) []i3.NodeID {
+	on := make(map[i3.NodeID]bool)
+	ws.FindChild(func(n *i3.Node) bool {
+		on[n.ID] = true
+		return false // visit all nodes
+	})
+	var ids []i3.NodeID
+	for _, id := range t.Global() {
+		if on[id] {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// FocusPrevious focuses the window which was focused before the currently
+// focused window. It is equivalent to Cycle(1).
+func (t *Tracker) FocusPrevious() error {
+	return t.Cycle(1)
+}
+
+// Cycle focuses the window which was focused n focus changes ago, wrapping
+// around at the end of the history. Negative n count from the end of the
+// history, i.e. Cycle(-1) focuses the least recently used window.
+func (t *Tracker) Cycle(n int) error {
+	ids := t.Global()
+	if len(ids) == 0 {
+		return nil
+	}
+	n %= len(ids)
+	if n < 0 {
+		n += len(ids)
+	}
+	return focus(ids[n])
+}
+
+// CycleWorkspace is like Cycle, but only considers the windows on workspace
+// ws (see Workspace).
+func (t *Tracker) CycleWorkspace(ws *i3.Node, n int) error {
+	ids := t.Workspace(ws)
+	if len(ids) == 0 {
+		return nil
+	}
+	n %= len(ids)
+	if n < 0 {
+		n += len(ids)
+	}
+	return focus(ids[n])
+}
+
+func focus(id i3.NodeID) error {
+	_, err := i3.RunCommand(fmt.Sprintf("[con_id=%d] focus", id))
+	return err
+}
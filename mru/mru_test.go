@@ -0,0 +1,61 @@
+package mru
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.i3wm.org/i3/v4"
+)
+
+func focusEvent(id i3.NodeID) *i3.WindowEvent {
+	return &i3.WindowEvent{
+		Change:    "focus",
+		Container: i3.Node{ID: id, Window: int64(id) + 1000},
+	}
+}
+
+func TestTracker(t *testing.T) {
+	t.Parallel()
+
+	statePath := filepath.Join(t.TempDir(), "mru.json")
+	tr, err := New(statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ev := range []i3.Event{
+		focusEvent(1),
+		focusEvent(2),
+		focusEvent(3),
+		&i3.WindowEvent{Change: "title", Container: i3.Node{ID: 4}},
+		focusEvent(1),
+		&i3.WindowEvent{Change: "close", Container: i3.Node{ID: 2}},
+	} {
+		if err := tr.Handle(ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if diff := cmp.Diff([]i3.NodeID{1, 3}, tr.Global()); diff != "" {
+		t.Fatalf("unexpected history: (-want +got)\n%s", diff)
+	}
+
+	ws := &i3.Node{
+		Type: i3.WorkspaceNode,
+		Nodes: []*i3.Node{
+			{ID: 3},
+			{ID: 5},
+		},
+	}
+	if diff := cmp.Diff([]i3.NodeID{3}, tr.Workspace(ws)); diff != "" {
+		t.Fatalf("unexpected workspace history: (-want +got)\n%s", diff)
+	}
+
+	// The history is persisted:
+	tr2, err := New(statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(tr.entries, tr2.entries); diff != "" {
+		t.Fatalf("unexpected persisted history: (-want +got)\n%s", diff)
+	}
+}
@@ -26,3 +26,22 @@ func GetOutputs() ([]Output, error) {
 	err = json.Unmarshal(reply.Payload, &outputs)
 	return outputs, err
 }
+
+// GetOutputsRaw is like GetOutputs, but additionally returns the JSON object
+// of each output as sent by i3, e.g. for fields which Output does not model.
+//
+// GetOutputsRaw is supported in i3 ≥ v4.0 (2011-07-31).
+func GetOutputsRaw() ([]Output, []json.RawMessage, error) {
+	reply, err := roundTrip(messageTypeGetOutputs, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var raw []json.RawMessage
+	if err := json.Unmarshal(reply.Payload, &raw); err != nil {
+		return nil, nil, err
+	}
+	var outputs []Output
+	err = json.Unmarshal(reply.Payload, &outputs)
+	return outputs, raw, err
+}
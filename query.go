@@ -0,0 +1,197 @@
+package i3
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Query is a compiled query expression for selecting nodes (or node fields)
+// from a layout tree. It is safe for concurrent use.
+//
+// Query expressions are either criteria (see Criteria), which select all
+// matching windows, or paths. A path consists of steps, each starting with /
+// (selecting children) or // (selecting descendants, including the current
+// node), followed by a node type (see NodeType) or * for any node type, and
+// any number of predicates in square brackets. Predicates use the criteria
+// syntax, but additionally accept any Node field by its JSON name. Values are
+// regular expressions, and fields without a value match if they are not
+// empty. A path may end in field steps, which select a field by its JSON name
+// instead of the node. Examples:
+//
+//	//con[class=Firefox]/rect
+//	/root/output[name=^HDMI]/con/workspace[focused]
+//	//workspace[urgent]/name
+//	[class="^URxvt$" title=vim]
+type Query struct {
+	criteria *Criteria // set if the expression consists of criteria
+	steps    []queryStep
+	field    []int // index sequence of the selected field, if any
+}
+
+type queryStep struct {
+	descendants bool
+	nodeType    NodeType // empty for *
+	predicates  []criterion
+}
+
+// nodeTypes are the node tests of query path steps.
+var nodeTypes = map[NodeType]bool{
+	Root:          true,
+	OutputNode:    true,
+	Con:           true,
+	FloatingCon:   true,
+	WorkspaceNode: true,
+	DockareaNode:  true,
+}
+
+// ParseQuery parses a query expression.
+func ParseQuery(expr string) (*Query, error) {
+	expr = strings.TrimSpace(expr)
+	if strings.HasPrefix(expr, "[") {
+		c, err := ParseCriteria(expr)
+		if err != nil {
+			return nil, err
+		}
+		return &Query{criteria: c}, nil
+	}
+	var (
+		q         Query
+		fieldType = reflect.TypeOf(Node{})
+	)
+	for s := expr; s != ""; {
+		if !strings.HasPrefix(s, "/") {
+			return nil, fmt.Errorf("expected / at %q", s)
+		}
+		var step queryStep
+		if strings.HasPrefix(s, "//") {
+			step.descendants = true
+			s = s[2:]
+		} else {
+			s = s[1:]
+		}
+		end := strings.IndexAny(s, "/[")
+		if end == -1 {
+			end = len(s)
+		}
+		name := s[:end]
+		s = s[end:]
+
+		if q.field != nil || (name != "*" && !nodeTypes[NodeType(name)]) {
+			// field step
+			if step.descendants {
+				return nil, fmt.Errorf("field %q must be selected with /, not //", name)
+			}
+			if fieldType.Kind() != reflect.Struct {
+				return nil, fmt.Errorf("cannot select %q from a non-struct field", name)
+			}
+			idx, typ, ok := fieldByName(fieldType, name)
+			if !ok {
+				return nil, fmt.Errorf("unknown node type or field %q", name)
+			}
+			if len(q.steps) == 0 {
+				return nil, fmt.Errorf("field %q must follow a node step", name)
+			}
+			q.field = append(q.field, idx...)
+			fieldType = typ
+			if strings.HasPrefix(s, "[") {
+				return nil, fmt.Errorf("field %q cannot have predicates", name)
+			}
+			continue
+		}
+
+		if name != "*" {
+			step.nodeType = NodeType(name)
+		}
+		for strings.HasPrefix(s, "[") {
+			var (
+				body string
+				err  error
+			)
+			body, s, err = scanBrackets(s)
+			if err != nil {
+				return nil, err
+			}
+			terms, err := parseCriteria(body, true)
+			if err != nil {
+				return nil, err
+			}
+			step.predicates = append(step.predicates, terms...)
+		}
+		q.steps = append(q.steps, step)
+	}
+	if len(q.steps) == 0 {
+		return nil, fmt.Errorf("empty query")
+	}
+	return &q, nil
+}
+
+// Nodes returns the nodes in the tree rooted at root which q matches, in
+// depth-first order, disregarding any field steps.
+func (q *Query) Nodes(root *Node) []*Node {
+	if q.criteria != nil {
+		return q.criteria.FindAll(root)
+	}
+	ctx := newMatchContext(root)
+	// The context of the first step is a virtual parent of root.
+	current := []*Node{{Nodes: []*Node{root}}}
+	for _, step := range q.steps {
+		var (
+			next []*Node
+			seen = make(map[*Node]bool)
+		)
+		visit := func(n *Node) {
+			if !seen[n] && step.match(n, ctx) {
+				seen[n] = true
+				next = append(next, n)
+			}
+		}
+		for _, n := range current {
+			if step.descendants {
+				n.FindChild(func(c *Node) bool {
+					visit(c)
+					return false // visit all nodes
+				})
+				continue
+			}
+			for _, c := range n.Nodes {
+				visit(c)
+			}
+			for _, c := range n.FloatingNodes {
+				visit(c)
+			}
+		}
+		current = next
+	}
+	return current
+}
+
+// Select returns the values which q selects from the tree rooted at root:
+// the matching nodes (as *Node), or the selected field of each matching node.
+func (q *Query) Select(root *Node) []interface{} {
+	nodes := q.Nodes(root)
+	values := make([]interface{}, len(nodes))
+	for i, n := range nodes {
+		if q.field == nil {
+			values[i] = n
+			continue
+		}
+		values[i] = reflect.ValueOf(n).Elem().FieldByIndex(q.field).Interface()
+	}
+	return values
+}
+
+func (s *queryStep) match(n *Node, ctx *matchContext) bool {
+	if n.Type == "" {
+		return false // the virtual parent of root
+	}
+	if s.nodeType != "" && n.Type != s.nodeType {
+		return false
+	}
+	for _, p := range s.predicates {
+		if !p.match(n, ctx) {
+			return false
+		}
+	}
+	return true
+}
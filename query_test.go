@@ -0,0 +1,171 @@
+package i3
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func nodeIDs(nodes []*Node) []NodeID {
+	ids := make([]NodeID, len(nodes))
+	for i, n := range nodes {
+		ids[i] = n.ID
+	}
+	return ids
+}
+
+func TestCriteria(t *testing.T) {
+	t.Parallel()
+
+	root := testTree()
+	for _, tt := range []struct {
+		criteria string
+		want     []NodeID
+	}{
+		{`[class="^XTerm$"]`, []NodeID{21}},
+		{`[class=e]`, []NodeID{21, 22}},
+		{`[instance="fire" title="Firefox"]`, []NodeID{22}},
+		{`[con_mark=term]`, []NodeID{21}},
+		{`[con_id=32]`, []NodeID{32}},
+		{`[con_id=__focused__]`, []NodeID{22}},
+		{`[class=__focused__]`, []NodeID{22}},
+		{`[id=1003]`, []NodeID{31}},
+		{`[id=0x3eb]`, []NodeID{31}},
+		{`[urgent]`, []NodeID{33}},
+		{`[urgent=latest]`, []NodeID{33}},
+		{`[floating]`, []NodeID{6}},
+		{`[tiling workspace="^2$"]`, []NodeID{31, 33}},
+		{`[workspace=__focused__]`, []NodeID{21, 22}},
+		{`[all]`, []NodeID{6, 21, 22, 31, 33}},
+	} {
+		c, err := ParseCriteria(tt.criteria)
+		if err != nil {
+			t.Errorf("ParseCriteria(%s): %v", tt.criteria, err)
+			continue
+		}
+		got := nodeIDs(c.FindAll(root))
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("%s: unexpected matches: (-want +got)\n%s", tt.criteria, diff)
+		}
+	}
+
+	// With two urgent windows, latest and oldest select one of them.
+	root = testTree()
+	root.FindChild(func(n *Node) bool { return n.ID == 21 }).Urgent = true
+	for _, tt := range []struct {
+		criteria string
+		want     []NodeID
+	}{
+		{`[urgent]`, []NodeID{21, 33}},
+		{`[urgent=latest]`, []NodeID{33}},
+		{`[urgent=oldest]`, []NodeID{21}},
+		{`[urgent=first class=mpv]`, []NodeID{}}, // like in i3, among all windows
+	} {
+		got := nodeIDs(MustParseCriteria(tt.criteria).FindAll(root))
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("%s: unexpected matches: (-want +got)\n%s", tt.criteria, diff)
+		}
+	}
+	if MustParseCriteria(`[urgent=latest]`).Match(root.FindChild(func(n *Node) bool { return n.ID == 33 })) {
+		t.Errorf("[urgent=latest]: Match unexpectedly matched without tree context")
+	}
+
+	for _, invalid := range []string{
+		``,
+		`[]`,
+		`class=foo`,
+		`[class]`,
+		`[class="foo]`,
+		`[layout=tabbed]`,
+		`[con_id=foo]`,
+		`[class=(]`,
+		`[con_mark=__focused__]`,
+		`[window_type=__focused__]`,
+		`[app_id=__focused__]`,
+		`[urgent=soon]`,
+	} {
+		if _, err := ParseCriteria(invalid); err == nil {
+			t.Errorf("ParseCriteria(%s) unexpectedly succeeded", invalid)
+		}
+	}
+}
+
+func TestCriteriaString(t *testing.T) {
+	t.Parallel()
+
+	c := MustParseCriteria(`[class="say \"hi\"\\w\\\\" con_id=5 floating]`)
+	if got, want := c.String(), `[class="say \"hi\"\\w\\\\" con_id="5" floating]`; got != want {
+		t.Fatalf("String() = %s, want %s", got, want)
+	}
+	if !c.Match(&Node{ID: 5, Window: 1, Floating: UserOn, WindowProperties: WindowProperties{Class: `say "hi"x\`}}) {
+		t.Fatalf("%s unexpectedly did not match", c)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	t.Parallel()
+
+	root := testTree()
+	for _, tt := range []struct {
+		expr string
+		want []NodeID
+	}{
+		{`/root`, []NodeID{1}},
+		{`/root/output`, []NodeID{2, 10}},
+		{`//workspace`, []NodeID{4, 20, 30}},
+		{`//workspace[urgent]`, []NodeID{30}},
+		{`//con[class=Firefox]`, []NodeID{22}},
+		{`//workspace[layout=tabbed]//con[window]`, []NodeID{31, 33}},
+		{`/root/output[name=^HDMI]/con/workspace[focus=22]`, []NodeID{20}},
+		{`//*[con_mark=term]`, []NodeID{21}},
+		{`//floating_con/con`, []NodeID{6}},
+		{`//con[workspace="^1: "][focused]`, []NodeID{22}},
+		{`[class=mpv]`, []NodeID{33}},
+	} {
+		q, err := ParseQuery(tt.expr)
+		if err != nil {
+			t.Errorf("ParseQuery(%s): %v", tt.expr, err)
+			continue
+		}
+		got := nodeIDs(q.Nodes(root))
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("%s: unexpected matches: (-want +got)\n%s", tt.expr, diff)
+		}
+	}
+
+	q, err := ParseQuery(`//con[class=Firefox]/rect`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []interface{}{Rect{X: 960, Width: 960, Height: 1080}}
+	if diff := cmp.Diff(want, q.Select(root)); diff != "" {
+		t.Errorf("unexpected Select result: (-want +got)\n%s", diff)
+	}
+
+	q, err = ParseQuery(`//workspace/rect/width`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []interface{}{int64(0), int64(1920), int64(1920)}
+	if diff := cmp.Diff(want, q.Select(root)); diff != "" {
+		t.Errorf("unexpected Select result: (-want +got)\n%s", diff)
+	}
+
+	for _, invalid := range []string{
+		``,
+		`con`,
+		`//nonsense`,
+		`/rect`,
+		`//con//rect`,
+		`//con/rect[x=0]`,
+		`//con[-]`,
+		`//con/-`,
+		`//con/name/foo`,
+		`//con[nodes=1]`,
+		`//con[class="x]`,
+	} {
+		if _, err := ParseQuery(invalid); err == nil {
+			t.Errorf("ParseQuery(%s) unexpectedly succeeded", invalid)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+// Package scratchpad manages windows in i3’s scratchpad: listing them,
+// showing and hiding specific ones, launching programs into the scratchpad
+// and positioning scratchpad windows on the current output.
+package scratchpad
+
+import (
+	"context"
+	"fmt"
+
+	"go.i3wm.org/i3/v4"
+)
+
+// Window is a window which was moved to the scratchpad.
+type Window struct {
+	*i3.Node
+
+	// Hidden is true if the window is on the hidden __i3_scratch workspace,
+	// and false if it is currently shown.
+	Hidden bool
+}
+
+// Windows returns all scratchpad windows in tree.
+//
+// i3 sets ScratchpadState on the floating container which wraps a scratchpad
+// window, not on the window itself, so Windows returns the windows inside such
+// floating containers.
+func Windows(tree i3.Tree) []Window {
+	var windows []Window
+	var walk func(n *i3.Node, hidden bool)
+	walk = func(n *i3.Node, hidden bool) {
+		if n.Type == i3.WorkspaceNode && n.Name == "__i3_scratch" {
+			hidden = true
+		}
+		if n.Type == i3.FloatingCon && n.ScratchpadState != "" && n.ScratchpadState != "none" {
+			n.FindChild(func(c *i3.Node) bool {
+				if c.Window != 0 {
+					windows = append(windows, Window{Node: c, Hidden: hidden})
+				}
+				return false // visit all nodes
+			})
+			return
+		}
+		for _, c := range n.Nodes {
+			walk(c, hidden)
+		}
+		for _, c := range n.FloatingNodes {
+			walk(c, hidden)
+		}
+	}
+	walk(tree.Root, false)
+	return windows
+}
+
+// find returns the first scratchpad window matching c.
+func find(c *i3.Criteria) (*Window, error) {
+	tree, err := i3.GetTree()
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range Windows(tree) {
+		if c.Match(w.Node) {
+			return &w, nil
+		}
+	}
+	return nil, nil
+}
+
+func run(id i3.NodeID, command string) error {
+	_, err := i3.RunCommand(fmt.Sprintf("[con_id=%d] %s", id, command))
+	return err
+}
+
+// Show shows the scratchpad window matching c on the current workspace, if
+// it is hidden.
+func Show(c *i3.Criteria) error {
+	w, err := find(c)
+	if err != nil || w == nil || !w.Hidden {
+		return err
+	}
+	return run(w.ID, "scratchpad show")
+}
+
+// Hide moves the scratchpad window matching c back to the scratchpad, if it
+// is shown.
+func Hide(c *i3.Criteria) error {
+	w, err := find(c)
+	if err != nil || w == nil || w.Hidden {
+		return err
+	}
+	return run(w.ID, "move scratchpad")
+}
+
+// Toggle shows the scratchpad window matching c if it is hidden, and hides
+// it otherwise.
+func Toggle(c *i3.Criteria) error {
+	w, err := find(c)
+	if err != nil || w == nil {
+		return err
+	}
+	if w.Hidden {
+		return run(w.ID, "scratchpad show")
+	}
+	return run(w.ID, "move scratchpad")
+}
+
+// SummonOrLaunch shows the first window matching c, moving it to the
+// scratchpad first if necessary. If no window matches c, SummonOrLaunch
+// starts argv, waits until its window appears (see i3.LaunchAndWait) and
+// moves that window to the scratchpad and shows it.
+//
+// SummonOrLaunch is supported in i3 ≥ v4.15 (2018-03-10).
+func SummonOrLaunch(ctx context.Context, c *i3.Criteria, argv []string) (*i3.Node, error) {
+	tree, err := i3.GetTree()
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range Windows(tree) {
+		if c.Match(w.Node) {
+			if w.Hidden {
+				return w.Node, run(w.ID, "scratchpad show")
+			}
+			return w.Node, nil
+		}
+	}
+	if matches := c.FindAll(tree.Root); len(matches) > 0 {
+		n := matches[0]
+		return n, run(n.ID, "move scratchpad, scratchpad show")
+	}
+	n, err := i3.LaunchAndWait(ctx, argv, c)
+	if err != nil {
+		return nil, err
+	}
+	return n, run(n.ID, "move scratchpad, scratchpad show")
+}
+
+// Placement describes the size and position of a window as fractions of its
+// output’s size, e.g. {X: 0.1, Y: 0.1, Width: 0.8, Height: 0.8}.
+type Placement struct {
+	X, Y, Width, Height float64
+}
+
+// Rect returns p in absolute coordinates on output rect out.
+func (p Placement) Rect(out i3.Rect) i3.Rect {
+	return i3.Rect{
+		X:      out.X + int64(p.X*float64(out.Width)),
+		Y:      out.Y + int64(p.Y*float64(out.Height)),
+		Width:  int64(p.Width * float64(out.Width)),
+		Height: int64(p.Height * float64(out.Height)),
+	}
+}
+
+// Place resizes and moves the (floating) window with the specified
+// container ID according to p, relative to the output of the focused
+// workspace.
+func Place(id i3.NodeID, p Placement) error {
+	workspaces, err := i3.GetWorkspaces()
+	if err != nil {
+		return err
+	}
+	outputs, err := i3.GetOutputs()
+	if err != nil {
+		return err
+	}
+	var output string
+	for _, ws := range workspaces {
+		if ws.Focused {
+			output = ws.Output
+		}
+	}
+	for _, o := range outputs {
+		if o.Name != output {
+			continue
+		}
+		r := p.Rect(o.Rect)
+		return run(id, fmt.Sprintf("resize set %d px %d px, move absolute position %d px %d px",
+			r.Width, r.Height, r.X, r.Y))
+	}
+	return fmt.Errorf("output %q of the focused workspace not found", output)
+}
@@ -0,0 +1,104 @@
+package scratchpad
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.i3wm.org/i3/v4"
+)
+
+func TestWindows(t *testing.T) {
+	t.Parallel()
+
+	tree := i3.Tree{Root: &i3.Node{
+		ID:   1,
+		Type: i3.Root,
+		Nodes: []*i3.Node{
+			{
+				ID:   2,
+				Name: "__i3",
+				Type: i3.OutputNode,
+				Nodes: []*i3.Node{{
+					ID:   3,
+					Type: i3.Con,
+					Nodes: []*i3.Node{{
+						ID:   4,
+						Name: "__i3_scratch",
+						Type: i3.WorkspaceNode,
+						FloatingNodes: []*i3.Node{{
+							ID:              5,
+							Type:            i3.FloatingCon,
+							ScratchpadState: "fresh",
+							Nodes: []*i3.Node{
+								{ID: 6, Type: i3.Con, Window: 1000, ScratchpadState: "none"},
+							},
+						}},
+					}},
+				}},
+			},
+			{
+				ID:   10,
+				Name: "HDMI-1",
+				Type: i3.OutputNode,
+				Nodes: []*i3.Node{{
+					ID:   11,
+					Type: i3.Con,
+					Nodes: []*i3.Node{{
+						ID:   12,
+						Name: "1",
+						Type: i3.WorkspaceNode,
+						Nodes: []*i3.Node{
+							{ID: 13, Type: i3.Con, Window: 1001, ScratchpadState: "none"},
+						},
+						FloatingNodes: []*i3.Node{
+							{
+								ID:              14,
+								Type:            i3.FloatingCon,
+								ScratchpadState: "changed",
+								Nodes: []*i3.Node{
+									{ID: 15, Type: i3.Con, Window: 1002, ScratchpadState: "none"},
+								},
+							},
+							{
+								// A floating window which is not in the scratchpad.
+								ID:              16,
+								Type:            i3.FloatingCon,
+								ScratchpadState: "none",
+								Nodes: []*i3.Node{
+									{ID: 17, Type: i3.Con, Window: 1003, ScratchpadState: "none"},
+								},
+							},
+						},
+					}},
+				}},
+			},
+		},
+	}}
+
+	type window struct {
+		ID     i3.NodeID
+		Hidden bool
+	}
+	var got []window
+	for _, w := range Windows(tree) {
+		got = append(got, window{ID: w.ID, Hidden: w.Hidden})
+	}
+	want := []window{
+		{ID: 6, Hidden: true},
+		{ID: 15, Hidden: false},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Windows: unexpected result: (-want +got)\n%s", diff)
+	}
+}
+
+func TestPlacementRect(t *testing.T) {
+	t.Parallel()
+
+	out := i3.Rect{X: 1920, Y: 0, Width: 2560, Height: 1440}
+	p := Placement{X: 0.1, Y: 0.25, Width: 0.8, Height: 0.5}
+	want := i3.Rect{X: 2176, Y: 360, Width: 2048, Height: 720}
+	if got := p.Rect(out); got != want {
+		t.Errorf("Rect(%v) = %v, want %v", out, got, want)
+	}
+}
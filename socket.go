@@ -2,6 +2,7 @@ package i3
 
 import (
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 	"math/rand"
@@ -14,7 +15,7 @@ import (
 
 // If your computer takes more than 10s to restart i3, it must be seriously
 // overloaded, in which case we are probably doing you a favor by erroring out.
-const reconnectTimeout = 10 * time.Second
+var reconnectTimeout = 10 * time.Second // overridden in tests
 
 // remote is a singleton containing the socket path and auto-detected byte order
 // which i3 is using. It is lazily initialized by getIPCSocket.
@@ -49,12 +50,14 @@ func getIPCSocket(updateSocketPath bool) (*socket, net.Conn, error) {
 		return nil, nil, err
 	}
 	remote.path = path
+	logger().Debug("connected to i3", "path", path)
 	if remote.order == nil {
 		remote.order, err = detectByteOrder(conn)
 		if err != nil {
 			conn.Close()
 			return nil, nil, err
 		}
+		logger().Debug("detected i3 byte order", "order", remote.order.String())
 	}
 
 	return &socket{conn: conn, order: remote.order}, conn, err
@@ -108,6 +111,22 @@ type header struct {
 	Type   messageType
 }
 
+// MaxMessageSize limits the payload size of messages received from i3 (in
+// bytes), so that a corrupted stream cannot make the process allocate up to
+// 4 GiB. Even the layout tree of large setups stays well below the default.
+var MaxMessageSize uint32 = 64 << 20
+
+// check validates a received message header.
+func (h *header) check() error {
+	if h.Magic != magic {
+		return &ProtocolError{Reason: fmt.Sprintf("invalid magic string %q", h.Magic[:])}
+	}
+	if h.Length > MaxMessageSize {
+		return &ProtocolError{Reason: fmt.Sprintf("message size %d exceeds MaxMessageSize (%d)", h.Length, MaxMessageSize)}
+	}
+	return nil
+}
+
 type message struct {
 	Type    messageType
 	Payload []byte
@@ -118,46 +137,162 @@ type socket struct {
 	order binary.ByteOrder
 }
 
-func (s *socket) recvMsg() (message, error) {
+// recvHeader receives and validates the header of a message. The payload
+// must be read from s.conn afterwards.
+func (s *socket) recvHeader() (header, error) {
+	var h header
 	if s == nil {
-		return message{}, fmt.Errorf("not connected")
+		return h, ErrNotConnected
 	}
-	var h header
 	if err := binary.Read(s.conn, s.order, &h); err != nil {
+		return h, err
+	}
+	return h, h.check()
+}
+
+func (s *socket) recvMsg() (message, error) {
+	h, err := s.recvHeader()
+	if err != nil {
 		return message{}, err
 	}
 	msg := message{
 		Type:    h.Type,
 		Payload: make([]byte, h.Length),
 	}
-	_, err := io.ReadFull(s.conn, msg.Payload)
-	return msg, err
+	if _, err := io.ReadFull(s.conn, msg.Payload); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return msg, &ProtocolError{
+				Reason: fmt.Sprintf("message payload shorter than %d bytes", h.Length),
+				Err:    io.ErrUnexpectedEOF,
+			}
+		}
+		return msg, err
+	}
+	return msg, nil
 }
 
-func (s *socket) roundTrip(t messageType, payload []byte) (message, error) {
+func (s *socket) sendMsg(t messageType, payload []byte) error {
 	if s == nil {
-		return message{}, fmt.Errorf("not connected")
+		return ErrNotConnected
 	}
 
 	if err := binary.Write(s.conn, s.order, &header{magic, uint32(len(payload)), t}); err != nil {
-		return message{}, err
+		return err
 	}
 	if len(payload) > 0 { // skip empty Write()s for net.Pipe
 		_, err := s.conn.Write(payload)
 		if err != nil {
-			return message{}, err
+			return err
 		}
 	}
+	return nil
+}
+
+func (s *socket) roundTrip(t messageType, payload []byte) (message, error) {
+	if err := s.sendMsg(t, payload); err != nil {
+		return message{}, err
+	}
 	return s.recvMsg()
 }
 
-// defaultSock is a singleton, lazily initialized by roundTrip. All
-// request/response messages are sent to i3 via this socket, whereas
-// subscriptions use their own connection.
-var defaultSock struct {
+// roundTripReader is like roundTrip, but passes the reply payload to fn as a
+// reader instead of reading it into memory, see recvReader.
+func (s *socket) roundTripReader(t messageType, payload []byte, fn func(io.Reader) error) (fnErr, err error) {
+	if err := s.sendMsg(t, payload); err != nil {
+		return nil, err
+	}
+	return s.recvReader(fn)
+}
+
+// recvReader receives a message and passes its payload to fn as a reader. The
+// part of the payload which fn does not read is discarded. fnErr is fn’s
+// error, if any, whereas err is an error of the connection.
+func (s *socket) recvReader(fn func(io.Reader) error) (fnErr, err error) {
+	h, err := s.recvHeader()
+	if err != nil {
+		return nil, err
+	}
+	r := &io.LimitedReader{R: s.conn, N: int64(h.Length)}
+	fnErr = fn(r)
+	// Keep the connection in sync for the next message.
+	_, err = io.Copy(io.Discard, r)
+	return fnErr, err
+}
+
+// PoolSize is the maximum number of connections over which requests are sent
+// to i3 concurrently, so that e.g. a slow GetTree does not delay other
+// requests. Set PoolSize before sending the first request; later changes
+// have no effect.
+var PoolSize = 4
+
+// pooledConn is a connection of the pool, lazily (re-)established by
+// withConn.
+type pooledConn struct {
 	sock *socket
 	conn net.Conn
-	mu   sync.Mutex
+}
+
+// pool is a singleton, lazily initialized by acquireConn. All
+// request/response messages are sent to i3 via connections of the pool,
+// whereas subscriptions use their own connection. All connections share the
+// byte order which getIPCSocket detects once.
+var pool struct {
+	once  sync.Once
+	conns chan *pooledConn // idle connections
+
+	mu      sync.Mutex // serializes IPCHooks.PoolWaiting calls
+	waiting int        // goroutines blocked in acquireConn
+}
+
+// acquireConn returns an idle connection of the pool, blocking until one is
+// available.
+func acquireConn() *pooledConn {
+	pool.once.Do(func() {
+		size := PoolSize
+		if size < 1 {
+			size = 1
+		}
+		pool.conns = make(chan *pooledConn, size)
+		for i := 0; i < size; i++ {
+			pool.conns <- &pooledConn{}
+		}
+	})
+	select {
+	case c := <-pool.conns:
+		return c // idle connection available, nothing to report
+	default:
+	}
+	addWaiting(1)
+	defer addWaiting(-1)
+	return <-pool.conns
+}
+
+// addWaiting updates the number of goroutines blocked in acquireConn and
+// reports it to IPCHooks.
+func addWaiting(delta int) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	pool.waiting += delta
+	if IPCHooks != nil {
+		IPCHooks.PoolWaiting(pool.waiting)
+	}
+}
+
+func releaseConn(c *pooledConn) {
+	pool.conns <- c
+}
+
+// checkMessageType returns an error if the message type is not yet supported
+// by the running i3 version.
+func checkMessageType(t messageType) error {
+	if t == messageTypeGetVersion {
+		return nil
+	}
+	v, ok := messageAtLeast[t]
+	if !ok {
+		return nil // unknown to this package, see SendMessage
+	}
+	return AtLeast(v.major, v.minor)
 }
 
 // roundTrip sends a message to i3 and returns the received result in a
@@ -165,29 +300,102 @@ var defaultSock struct {
 func roundTrip(t messageType, payload []byte) (message, error) {
 	// Error out early in case the message type is not yet supported by the
 	// running i3 version.
-	if t != messageTypeGetVersion {
-		if err := AtLeast(messageAtLeast[t].major, messageAtLeast[t].minor); err != nil {
-			return message{}, err
-		}
+	if err := checkMessageType(t); err != nil {
+		return message{}, err
+	}
+	rt := startRoundTrip(t, payload)
+	msg, err := withConn(func(s *socket) (message, error) {
+		return s.roundTrip(t, payload)
+	})
+	endRoundTrip(rt, len(msg.Payload), err)
+	return msg, err
+}
+
+// SendMessage sends a message of the specified type (see
+// https://i3wm.org/docs/ipc.html#_sending_messages_to_i3) with the specified
+// payload to i3 and returns the payload of the reply. Use SendMessage to
+// experiment with message types which this package does not implement yet,
+// or to access raw replies.
+func SendMessage(t uint32, payload []byte) ([]byte, error) {
+	reply, err := roundTrip(messageType(t), payload)
+	if err != nil {
+		return nil, err
+	}
+	return reply.Payload, nil
+}
+
+// roundTripReader is like roundTrip, but passes the reply payload to fn as a
+// reader, see socket.recvReader.
+func roundTripReader(t messageType, payload []byte, fn func(io.Reader) error) error {
+	if err := checkMessageType(t); err != nil {
+		return err
 	}
+	rt := startRoundTrip(t, payload)
+	var (
+		fnErr     error
+		replySize int
+	)
+	_, err := withConn(func(s *socket) (message, error) {
+		var err error
+		fnErr, err = s.roundTripReader(t, payload, func(r io.Reader) error {
+			// recvReader passes the payload as *io.LimitedReader.
+			if lr, ok := r.(*io.LimitedReader); ok {
+				replySize = int(lr.N)
+			}
+			return fn(r)
+		})
+		return message{}, err
+	})
+	if err == nil {
+		err = fnErr
+	}
+	endRoundTrip(rt, replySize, err)
+	return err
+}
 
-	defaultSock.mu.Lock()
-	defer defaultSock.mu.Unlock()
+// withConn calls fn with a connection of the pool, reconnecting and retrying
+// as necessary.
+func withConn(fn func(*socket) (message, error)) (message, error) {
+	c := acquireConn()
+	defer releaseConn(c)
 
 Outer:
 	for {
-		msg, err := defaultSock.sock.roundTrip(t, payload)
+		msg, err := fn(c.sock)
 		if err == nil {
 			return msg, nil // happy path: success
 		}
+		var perr *ProtocolError
+		if errors.As(err, &perr) {
+			// Retrying would likely yield the same reply, so only drop the
+			// connection, which is out of sync.
+			logger().Debug("dropping out-of-sync connection", "error", err)
+			c.conn.Close()
+			c.sock, c.conn = nil, nil
+			return msg, err
+		}
+		var uerr *unretriableError
+		if errors.As(err, &uerr) {
+			logger().Debug("dropping failed connection without retrying", "error", err)
+			c.conn.Close()
+			c.sock, c.conn = nil, nil
+			return msg, fmt.Errorf("%w: %w", ErrNotConnected, uerr.err)
+		}
 
 		// reconnect
 		start := time.Now()
-		for time.Since(start) < reconnectTimeout && (defaultSock.sock == nil || i3Running()) {
-			if defaultSock.sock != nil {
-				defaultSock.conn.Close()
+		for attempt := 1; time.Since(start) < reconnectTimeout && (c.sock == nil || i3Running()); attempt++ {
+			if c.sock != nil || attempt > 1 {
+				logger().Debug("reconnecting to i3", "attempt", attempt, "error", err)
+				hookReconnect(attempt, err)
+			}
+			if c.sock != nil {
+				c.conn.Close()
+			}
+			c.sock, c.conn, err = getIPCSocket(c.sock != nil)
+			if err == nil {
+				err = refreshVersion(c.sock)
 			}
-			defaultSock.sock, defaultSock.conn, err = getIPCSocket(defaultSock.sock != nil)
 			if err == nil {
 				continue Outer
 			}
@@ -195,6 +403,16 @@ Outer:
 			// Reconnect within [10, 20) ms to prevent CPU-starving i3.
 			time.Sleep(time.Duration(10+rand.Int63n(10)) * time.Millisecond)
 		}
-		return msg, err
+		return msg, notConnected(err)
+	}
+}
+
+// notConnected wraps err, an error of (re-)connecting to i3, in
+// ErrNotConnected, unless it already does or is a *ProtocolError.
+func notConnected(err error) error {
+	var perr *ProtocolError
+	if errors.Is(err, ErrNotConnected) || errors.As(err, &perr) {
+		return err
 	}
+	return fmt.Errorf("%w: %w", ErrNotConnected, err)
 }
@@ -0,0 +1,86 @@
+package i3
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+func TestRecvMsgProtocolError(t *testing.T) {
+	t.Parallel()
+
+	order := binary.LittleEndian
+	valid := msgBytes(order, messageReplyTypeCommand, `[{"success": true}]`)
+	badMagic := append([]byte("i3-ipx"), valid[6:]...)
+	var oversized bytes.Buffer
+	if err := binary.Write(&oversized, order, &header{magic, 1 << 31, messageReplyTypeCommand}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name    string
+		input   []byte
+		wantErr bool
+	}{
+		{name: "valid", input: valid},
+		{name: "bad magic", input: badMagic, wantErr: true},
+		{name: "oversized", input: oversized.Bytes(), wantErr: true},
+		{name: "truncated", input: valid[:len(valid)-1], wantErr: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			sock := &socket{conn: bytes.NewBuffer(tt.input), order: order}
+			msg, err := sock.recvMsg()
+			var perr *ProtocolError
+			if got := errors.As(err, &perr); got != tt.wantErr {
+				t.Fatalf("recvMsg: got err %v, want protocol error: %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && string(msg.Payload) != `[{"success": true}]` {
+				t.Errorf("recvMsg: unexpected payload %q", msg.Payload)
+			}
+		})
+	}
+}
+
+func TestRawReplies(t *testing.T) {
+	const (
+		barConfig = `{"id": "bar-0", "mode": "dock", "future_field": 1}`
+		config    = `{"config": "bar {}\n", "future_field": 2}`
+		version   = `{"major": 4, "minor": 24, "patch": 0, "human_readable": "4.24", "future_field": 3}`
+	)
+	useFakeI3(t, func(typ uint32, payload []byte) []byte {
+		switch messageType(typ) {
+		case messageTypeGetBarConfig:
+			return []byte(barConfig)
+		case messageTypeGetConfig:
+			return []byte(config)
+		case messageTypeGetVersion:
+			return []byte(version)
+		}
+		return nil
+	})
+
+	cfg, raw, err := GetBarConfigRaw("bar-0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ID != "bar-0" || cfg.Colors.Background != "#000000" || string(raw) != barConfig {
+		t.Errorf("GetBarConfigRaw: got %+v, %s", cfg, raw)
+	}
+
+	c, raw, err := GetConfigRaw()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Config != "bar {}\n" || string(raw) != config {
+		t.Errorf("GetConfigRaw: got %+v, %s", c, raw)
+	}
+
+	v, raw, err := GetVersionRaw()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Minor != 24 || string(raw) != version {
+		t.Errorf("GetVersionRaw: got %+v, %s", v, raw)
+	}
+}
@@ -3,9 +3,9 @@ package i3
 import (
 	"encoding/json"
 	"fmt"
-	"log"
 	"math/rand"
 	"net"
+	"sync"
 	"time"
 )
 
@@ -51,6 +51,15 @@ type WindowEvent struct {
 // See https://i3wm.org/docs/ipc.html#_barconfig_update_event for more details.
 type BarconfigUpdateEvent BarConfig
 
+// BarStateUpdateEvent is sent when the visibility of a bar in hide mode
+// changes because its modifier key was pressed or released.
+//
+// The event uses the same event number as sway’s bar_state_update event.
+type BarStateUpdateEvent struct {
+	ID                string `json:"id"`
+	VisibleByModifier bool   `json:"visible_by_modifier"`
+}
+
 // BindingEvent contains details about various binding-related changes.
 //
 // See https://i3wm.org/docs/ipc.html#_binding_event for more details.
@@ -82,6 +91,24 @@ type TickEvent struct {
 	Payload string `json:"payload"`
 }
 
+// RawEvent is an event of a type which this package does not implement, e.g.
+// an event introduced by an i3 version newer than this package, or by an i3
+// fork. Decode the payload yourself, e.g. with json.Unmarshal.
+type RawEvent struct {
+	Type    uint32 // without the event flag (highest bit)
+	Payload json.RawMessage
+}
+
+// Err returns an *UnknownEventError for e, for code which treats events of
+// unknown types as errors:
+//
+//	if raw, ok := recv.Event().(*i3.RawEvent); ok {
+//		return raw.Err()
+//	}
+func (e *RawEvent) Err() error {
+	return &UnknownEventError{Type: e.Type, Payload: e.Payload}
+}
+
 type eventReplyType int
 
 const (
@@ -95,20 +122,30 @@ const (
 	eventReplyTypeTick
 )
 
+// eventReplyTypeBarStateUpdate is not contiguous with the other event reply
+// types, as it was introduced by sway, which uses the event numbers in
+// between for its own events.
+const eventReplyTypeBarStateUpdate eventReplyType = 20
+
 const (
 	eventFlagMask = uint32(0x80000000)
 	eventTypeMask = ^eventFlagMask
 )
 
-// EventReceiver is not safe for concurrent use.
+// EventReceiver is not safe for concurrent use, with the exception of Close,
+// which may be called concurrently with Next, e.g. to stop a goroutine which
+// is blocked in Next.
 type EventReceiver struct {
 	types     []EventType // for re-subscribing on io.EOF
-	sock      *socket
-	conn      net.Conn
 	ev        Event
-	err       error
+	raw       []byte // payload of ev
 	reconnect bool
-	closed    bool
+
+	mu     sync.Mutex // guards the following fields, see Close
+	sock   *socket
+	conn   net.Conn
+	err    error
+	closed bool
 }
 
 // Event returns the most recent event received from i3 by a call to Next.
@@ -116,49 +153,81 @@ func (r *EventReceiver) Event() Event {
 	return r.ev
 }
 
+// RawPayload returns the JSON payload of the most recent event received from
+// i3 by a call to Next, e.g. for fields which the event types do not model.
+func (r *EventReceiver) RawPayload() []byte {
+	return r.raw
+}
+
 func (r *EventReceiver) subscribe() error {
-	var err error
+	r.mu.Lock()
 	if r.conn != nil {
 		r.conn.Close()
 	}
+	r.mu.Unlock()
 	if wasRestart {
 		r.reconnect = false
 	}
-	r.sock, r.conn, err = getIPCSocket(r.reconnect)
+	sock, conn, err := getIPCSocket(r.reconnect)
 	r.reconnect = true
+	r.mu.Lock()
+	closed := r.closed
+	if closed {
+		if conn != nil {
+			conn.Close()
+		}
+		sock, conn = nil, nil
+	}
+	r.sock, r.conn = sock, conn
+	r.mu.Unlock()
+	if closed {
+		return net.ErrClosed
+	}
 	if err != nil {
-		return err
+		return &SubscribeError{Types: r.types, Err: notConnected(err)}
+	}
+	// Should Close be called from here on, it closes conn, which makes the
+	// following requests fail.
+	if err := refreshVersion(sock); err != nil {
+		return &SubscribeError{Types: r.types, Err: notConnected(err)}
 	}
 	payload, err := json.Marshal(r.types)
 	if err != nil {
 		return err
 	}
-	b, err := r.sock.roundTrip(messageTypeSubscribe, payload)
+	b, err := sock.roundTrip(messageTypeSubscribe, payload)
 	if err != nil {
-		return err
+		return &SubscribeError{Types: r.types, Err: notConnected(err)}
 	}
 	var reply struct {
 		Success bool `json:"success"`
 	}
 	if err := json.Unmarshal(b.Payload, &reply); err != nil {
-		return err
+		return &ProtocolError{Reason: "malformed SUBSCRIBE reply", Err: err}
 	}
 	if !reply.Success {
-		return fmt.Errorf("could not subscribe, check the i3 log")
+		return &SubscribeError{Types: r.types}
 	}
+	r.mu.Lock()
 	r.err = nil
+	r.mu.Unlock()
 	return nil
 }
 
 func (r *EventReceiver) next() (Event, error) {
-	reply, err := r.sock.recvMsg()
+	r.mu.Lock()
+	sock := r.sock
+	r.mu.Unlock()
+	reply, err := sock.recvMsg()
 	if err != nil {
 		return nil, err
 	}
+	r.raw = reply.Payload
 	if (uint32(reply.Type) & eventFlagMask) == 0 {
-		return nil, fmt.Errorf("unexpectedly did not receive an event")
+		return nil, &ProtocolError{Reason: fmt.Sprintf("unexpectedly received reply type %d instead of an event", reply.Type)}
 	}
 	t := uint32(reply.Type) & eventTypeMask
+	hookEvent(t, len(reply.Payload))
 	switch eventReplyType(t) {
 	case eventReplyTypeWorkspace:
 		var e WorkspaceEvent
@@ -191,8 +260,12 @@ func (r *EventReceiver) next() (Event, error) {
 	case eventReplyTypeTick:
 		var e TickEvent
 		return &e, json.Unmarshal(reply.Payload, &e)
+
+	case eventReplyTypeBarStateUpdate:
+		var e BarStateUpdateEvent
+		return &e, json.Unmarshal(reply.Payload, &e)
 	}
-	return nil, fmt.Errorf("BUG: event reply type %d not implemented yet", t)
+	return &RawEvent{Type: t, Payload: reply.Payload}, nil
 }
 
 // Next advances the EventReceiver to the next event, which will then be
@@ -205,35 +278,72 @@ func (r *EventReceiver) next() (Event, error) {
 // UNIX socket buffer is full of unprocessed events.
 func (r *EventReceiver) Next() bool {
 Outer:
-	for r.err == nil {
-		r.ev, r.err = r.next()
-		if r.err == nil {
+	for {
+		r.mu.Lock()
+		sock, err, closed := r.sock, r.err, r.closed
+		r.mu.Unlock()
+		if err != nil || closed {
+			return false
+		}
+		r.ev, err = r.next()
+		if err == nil {
 			return true // happy path
 		}
-
-		if r.closed {
+		if r.fail(err) {
 			return false
 		}
+		reconnecting := sock != nil // as opposed to the initial subscribe
+		if reconnecting {
+			logger().Debug("event subscription interrupted, re-subscribing; events are dropped in the meantime",
+				"types", r.types,
+				"error", err)
+		}
 
 		// reconnect
 		start := time.Now()
-		for time.Since(start) < reconnectTimeout && (r.sock == nil || i3Running()) {
-			if err := r.subscribe(); err == nil {
+		for attempt := 1; time.Since(start) < reconnectTimeout && (!r.connected() || i3Running()); attempt++ {
+			if reconnecting || attempt > 1 {
+				hookReconnect(attempt, err)
+			}
+			if err = r.subscribe(); err == nil {
 				continue Outer
-			} else {
-				r.err = err
+			}
+			if r.fail(err) {
+				return false
 			}
 
 			// Reconnect within [10, 20) ms to prevent CPU-starving i3.
 			time.Sleep(time.Duration(10+rand.Int63n(10)) * time.Millisecond)
 		}
+		return false
 	}
-	return r.err == nil
+}
+
+// fail records err as the error of r, unless r was closed, in which case
+// Close already recorded the error. fail returns whether r was closed.
+func (r *EventReceiver) fail(err error) (closed bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.closed {
+		r.err = err
+	}
+	return r.closed
+}
+
+func (r *EventReceiver) connected() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.sock != nil
 }
 
 // Close closes the connection to i3. If you don’t ever call Close, you must
 // consume events via Next to prevent i3 from deadlocking.
+//
+// Close may be called from another goroutine while Next is blocked waiting
+// for an event, in which case Next returns false.
 func (r *EventReceiver) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	r.closed = true
 	if r.conn != nil {
 		if r.err == nil {
@@ -261,6 +371,7 @@ const (
 	BindingEventType         EventType = "binding"          // since 4.9
 	ShutdownEventType        EventType = "shutdown"         // since 4.14
 	TickEventType            EventType = "tick"             // since 4.15
+	BarStateUpdateEventType  EventType = "bar_state_update" // since 4.24
 )
 
 type majorMinor struct {
@@ -277,6 +388,7 @@ var eventAtLeast = map[EventType]majorMinor{
 	BindingEventType:         {4, 9},
 	ShutdownEventType:        {4, 14},
 	TickEventType:            {4, 15},
+	BarStateUpdateEventType:  {4, 24},
 }
 
 // Subscribe returns an EventReceiver for receiving events of the specified
@@ -286,12 +398,19 @@ var eventAtLeast = map[EventType]majorMinor{
 // call Subscribe once per event type, so that you can use type assertions
 // instead of type switches.
 //
+// To subscribe to events which this package does not implement yet, convert
+// their name, e.g. EventType("input"). Such events are delivered as *RawEvent.
+//
 // Subscribe is supported in i3 ≥ v4.0 (2011-07-31).
 func Subscribe(eventTypes ...EventType) *EventReceiver {
 	// Error out early in case any requested event type is not yet supported by
 	// the running i3 version.
 	for _, t := range eventTypes {
-		if err := AtLeast(eventAtLeast[t].major, eventAtLeast[t].minor); err != nil {
+		v, ok := eventAtLeast[t]
+		if !ok {
+			continue // unknown to this package, leave the check to i3
+		}
+		if err := AtLeast(v.major, v.minor); err != nil {
 			return &EventReceiver{err: err}
 		}
 	}
@@ -320,10 +439,10 @@ func restart(firstAttempt bool) error {
 		Success bool `json:"success"`
 	}
 	if err := json.Unmarshal(b.Payload, &sreply); err != nil {
-		return err
+		return &ProtocolError{Reason: "malformed SUBSCRIBE reply", Err: err}
 	}
 	if !sreply.Success {
-		return fmt.Errorf("could not subscribe, check the i3 log")
+		return &SubscribeError{Types: []EventType{ShutdownEventType}}
 	}
 	rreply, err := sock.roundTrip(messageTypeRunCommand, []byte("restart"))
 	if err != nil {
@@ -346,7 +465,7 @@ func restart(firstAttempt bool) error {
 	}
 	t := uint32(rreply.Type) & eventTypeMask
 	if got, want := eventReplyType(t), eventReplyTypeShutdown; got != want {
-		return fmt.Errorf("unexpected reply type: got %d, want %d", got, want)
+		return &ProtocolError{Reason: fmt.Sprintf("unexpected reply type: got %d, want %d", got, want)}
 	}
 	return nil // shutdown event received
 }
@@ -368,7 +487,7 @@ func Restart() error {
 		return err
 	}
 
-	log.Println("preventing any further X11 connections to work around issue #3")
+	logger().Info("preventing any further X11 connections to work around issue #3")
 	wasRestart = true
 
 	var (
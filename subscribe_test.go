@@ -1,8 +1,12 @@
 package i3
 
 import (
+	"bytes"
 	"context"
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"net"
 	"os"
 	"os/exec"
 	"sync"
@@ -184,3 +188,76 @@ func TestSubscribe(t *testing.T) {
 		t.Fatal(err.Error())
 	}
 }
+
+func TestRawEvent(t *testing.T) {
+	t.Parallel()
+
+	order := binary.LittleEndian
+	var conn bytes.Buffer
+	conn.Write(msgBytes(order, messageType(eventFlagMask|42), `{"change": "new"}`))
+	conn.Write(msgBytes(order, messageType(eventFlagMask|uint32(eventReplyTypeTick)), `{"first": true}`))
+	r := &EventReceiver{sock: &socket{conn: &conn, order: order}}
+
+	ev, err := r.next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, ok := ev.(*RawEvent)
+	if !ok {
+		t.Fatalf("next: got %T, want *RawEvent", ev)
+	}
+	if raw.Type != 42 || string(raw.Payload) != `{"change": "new"}` {
+		t.Errorf("next: unexpected RawEvent %+v", raw)
+	}
+	if got, want := string(r.RawPayload()), `{"change": "new"}`; got != want {
+		t.Errorf("RawPayload: got %q, want %q", got, want)
+	}
+	var unknown *UnknownEventError
+	if err := raw.Err(); !errors.As(err, &unknown) || unknown.Type != 42 {
+		t.Errorf("Err: got %v, want *UnknownEventError for type 42", err)
+	}
+
+	// The receiver keeps working after an unknown event.
+	ev, err = r.next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tick, ok := ev.(*TickEvent); !ok || !tick.First {
+		t.Errorf("next: got %#v, want first TickEvent", ev)
+	}
+}
+
+func TestCloseConcurrently(t *testing.T) {
+	t.Parallel()
+
+	order := binary.LittleEndian
+	client, server := net.Pipe()
+	defer server.Close()
+	r := &EventReceiver{sock: &socket{conn: client, order: order}, conn: client}
+
+	next := make(chan bool)
+	go func() {
+		next <- r.Next()
+	}()
+	server.Write(msgBytes(order, messageType(eventFlagMask|uint32(eventReplyTypeTick)), `{"first": true}`))
+	if !<-next {
+		t.Fatalf("Next: unexpectedly returned false: %v", r.Close())
+	}
+
+	// Close while Next is blocked waiting for the next event.
+	go func() {
+		next <- r.Next()
+	}()
+	time.Sleep(10 * time.Millisecond)
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case ok := <-next:
+		if ok {
+			t.Errorf("Next after Close: got true, want false")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Next did not return after Close")
+	}
+}
@@ -0,0 +1,368 @@
+// Package swallow implements window swallowing: when a program started from a
+// terminal opens a window, the terminal window is hidden (moved to the
+// scratchpad), and when that window closes, the terminal window is restored
+// in its place.
+package swallow
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+
+	"go.i3wm.org/i3/v4"
+	"go.i3wm.org/i3/v4/internal/daemon"
+)
+
+// Swallower hides terminal windows while windows of their child processes are
+// open. Windows are associated with processes via their _NET_WM_PID property,
+// so swallowing only works for X11 windows.
+type Swallower struct {
+	// Terminals lists the window classes of terminal emulators. If empty,
+	// DefaultTerminals is used.
+	Terminals []string
+
+	// Allow lists the window classes which swallow their terminal. If
+	// empty, all windows (except those in Deny) swallow their terminal.
+	Allow []string
+
+	// Deny lists the window classes which never swallow their terminal.
+	Deny []string
+
+	swallowed map[i3.NodeID]swallowing // child window container → terminal
+	remap     bool                     // container IDs are stale, see remapIDs
+	tree      i3.Tree                  // last known layout tree
+	pids      map[int64]int            // X11 window → process ID
+
+	// windowPID and parentPID are overridden in tests.
+	windowPID func(window int64) (int, error)
+	parentPID func(pid int) (int, error)
+}
+
+// DefaultTerminals lists the window classes of common terminal emulators.
+// Windows of other classes never swallow, so that e.g. a program started from
+// a launcher or a file manager does not hide the launcher’s window.
+var DefaultTerminals = []string{
+	"Alacritty",
+	"Gnome-terminal",
+	"kitty",
+	"konsole",
+	"Lxterminal",
+	"Mate-terminal",
+	"org.wezfurlong.wezterm",
+	"St",
+	"st-256color",
+	"Terminator",
+	"Tilix",
+	"URxvt",
+	"UXTerm",
+	"XTerm",
+	"Xfce4-terminal",
+}
+
+// swallowing is the terminal which a window swallowed. Container IDs change
+// when i3 restarts, whereas X11 window IDs do not, so both are tracked.
+type swallowing struct {
+	terminal       i3.NodeID
+	window         int64 // X11 window of the swallowing window
+	terminalWindow int64
+}
+
+// swallows returns whether windows of the specified class swallow their
+// terminal.
+func (s *Swallower) swallows(class string) bool {
+	if slices.Contains(s.Deny, class) {
+		return false
+	}
+	return len(s.Allow) == 0 || slices.Contains(s.Allow, class)
+}
+
+func (s *Swallower) pid(window int64) (int, error) {
+	if pid, ok := s.pids[window]; ok {
+		return pid, nil
+	}
+	windowPID := s.windowPID
+	if windowPID == nil {
+		windowPID = i3.WindowPID
+	}
+	pid, err := windowPID(window)
+	if err != nil {
+		return 0, err
+	}
+	if s.pids == nil {
+		s.pids = make(map[int64]int)
+	}
+	s.pids[window] = pid
+	return pid, nil
+}
+
+// Terminal returns the terminal window in tree whose process is the closest
+// ancestor of the process of window w, or nil if there is none.
+func (s *Swallower) Terminal(tree i3.Tree, w *i3.Node) *i3.Node {
+	if w.Window == 0 {
+		return nil
+	}
+	pid, err := s.pid(w.Window)
+	if err != nil {
+		return nil
+	}
+	parentPID := s.parentPID
+	if parentPID == nil {
+		parentPID = ParentPID
+	}
+	depth := make(map[int]int) // ancestor process ID → distance from pid
+	for d := 1; pid > 1; d++ {
+		if pid, err = parentPID(pid); err != nil {
+			break
+		}
+		depth[pid] = d
+	}
+	terminals := s.Terminals
+	if len(terminals) == 0 {
+		terminals = DefaultTerminals
+	}
+	hidden := make(map[i3.NodeID]bool)
+	for _, sw := range s.swallowed {
+		hidden[sw.terminal] = true
+	}
+	var (
+		terminal *i3.Node
+		best     int
+	)
+	tree.Root.FindChild(func(n *i3.Node) bool {
+		if n.Window == 0 || n.ID == w.ID || hidden[n.ID] {
+			return false
+		}
+		if !slices.Contains(terminals, n.WindowProperties.Class) {
+			return false
+		}
+		pid, err := s.pid(n.Window)
+		if err != nil {
+			return false
+		}
+		if d, ok := depth[pid]; ok && (terminal == nil || d < best) {
+			terminal, best = n, d
+		}
+		return false // visit all nodes
+	})
+	return terminal
+}
+
+// RestoreCommand returns the i3 command which shows the hidden terminal
+// window with container ID terminal in the place of the window with
+// container ID w. The place of w is looked up in before (a layout tree from
+// before w closed), which is then validated against now (the current layout
+// tree): neighbors which no longer exist are not used, and renamed workspaces
+// are referred to by their current name.
+func RestoreCommand(before, now i3.Tree, w, terminal i3.NodeID) string {
+	show := fmt.Sprintf("[con_id=%d] scratchpad show, floating disable", terminal)
+	if before.Root == nil || now.Root == nil {
+		return show
+	}
+	path := before.Root.PathTo(w)
+	if len(path) < 2 {
+		return show
+	}
+	parent := path[len(path)-2]
+	var workspace *i3.Node
+	for _, n := range path {
+		if n.Type == i3.WorkspaceNode {
+			workspace = n
+		}
+	}
+	if workspace == nil {
+		return show
+	}
+	if path := now.Root.PathTo(workspace.ID); path != nil {
+		workspace = path[len(path)-1]
+	}
+	exists := func(n *i3.Node) bool {
+		return n.Window != 0 && now.Root.PathTo(n.ID) != nil
+	}
+	idx := -1
+	for i, c := range parent.Nodes {
+		if c.ID == w {
+			idx = i
+		}
+	}
+	// Restore the terminal next to a neighboring window, which is marked
+	// temporarily. Moving to a split container would move into it instead.
+	mark := fmt.Sprintf("_swallow_%d", terminal)
+	switch {
+	case idx > 0 && exists(parent.Nodes[idx-1]):
+		// “move to mark” places the terminal after the neighbor.
+		neighbor := parent.Nodes[idx-1].ID
+		return fmt.Sprintf("[con_id=%d] mark --add %s; %s, move container to mark %s; [con_id=%d] unmark %s",
+			neighbor, mark, show, mark, neighbor, mark)
+
+	case idx == 0 && len(parent.Nodes) > 1 && exists(parent.Nodes[1]):
+		// Place the terminal after the neighbor, then swap them.
+		neighbor := parent.Nodes[1].ID
+		return fmt.Sprintf("[con_id=%d] mark --add %s; %s, move container to mark %s, swap container with con_id %d; [con_id=%d] unmark %s",
+			neighbor, mark, show, mark, neighbor, neighbor, mark)
+	}
+	return fmt.Sprintf("%s, move container to workspace %s", show, i3.Quote(workspace.Name))
+}
+
+// Handle swallows or restores terminal windows in response to ev. Run calls
+// Handle, so only call Handle if you read events yourself.
+func (s *Swallower) Handle(ev i3.Event) error {
+	switch ev := ev.(type) {
+	case *i3.ShutdownEvent:
+		// Container IDs are not stable across restarts.
+		s.remap = true
+		s.tree = i3.Tree{}
+		return nil
+
+	case *i3.WindowEvent:
+		if s.remap {
+			tree, err := i3.GetTree()
+			if err != nil {
+				return err
+			}
+			for _, t := range s.remapIDs(tree) {
+				if err := run(fmt.Sprintf("[con_id=%d] scratchpad show, floating disable", t)); err != nil {
+					return err
+				}
+			}
+			if err := s.refresh(); err != nil {
+				return err
+			}
+		}
+		switch ev.Change {
+		case "new":
+			tree, err := i3.GetTree()
+			if err != nil {
+				return err
+			}
+			s.tree = tree
+			if !s.swallows(ev.Container.WindowProperties.Class) {
+				return nil
+			}
+			t := s.Terminal(tree, &ev.Container)
+			if t == nil {
+				return nil
+			}
+			if s.swallowed == nil {
+				s.swallowed = make(map[i3.NodeID]swallowing)
+			}
+			s.swallowed[ev.Container.ID] = swallowing{
+				terminal:       t.ID,
+				window:         ev.Container.Window,
+				terminalWindow: t.Window,
+			}
+			return run(fmt.Sprintf("[con_id=%d] move scratchpad", t.ID))
+
+		case "close":
+			delete(s.pids, ev.Container.Window)
+			sw, ok := s.swallowed[ev.Container.ID]
+			if !ok {
+				// A terminal might close while hidden.
+				for w, sw := range s.swallowed {
+					if sw.terminal == ev.Container.ID {
+						delete(s.swallowed, w)
+					}
+				}
+				return s.refresh()
+			}
+			delete(s.swallowed, ev.Container.ID)
+			// s.tree knows where the window was, but might be stale.
+			now, err := i3.GetTree()
+			if err != nil {
+				return err
+			}
+			if err := run(RestoreCommand(s.tree, now, ev.Container.ID, sw.terminal)); err != nil {
+				return err
+			}
+			return s.refresh()
+
+		case "move", "floating", "fullscreen_mode":
+			return s.refresh()
+		}
+	}
+	return nil
+}
+
+// remapIDs updates the container IDs after i3 restarted, based on X11 window
+// IDs. Windows which no longer exist are forgotten. remapIDs returns the
+// container IDs of hidden terminals whose swallowing window closed in the
+// meantime, which should be shown again.
+func (s *Swallower) remapIDs(tree i3.Tree) (orphaned []i3.NodeID) {
+	cons := make(map[int64]i3.NodeID)
+	tree.Root.FindChild(func(n *i3.Node) bool {
+		if n.Window != 0 {
+			cons[n.Window] = n.ID
+		}
+		return false // visit all nodes
+	})
+	swallowed := make(map[i3.NodeID]swallowing)
+	for _, sw := range s.swallowed {
+		w, wok := cons[sw.window]
+		t, tok := cons[sw.terminalWindow]
+		switch {
+		case wok && tok:
+			sw.terminal = t
+			swallowed[w] = sw
+		case tok:
+			orphaned = append(orphaned, t)
+		}
+	}
+	s.swallowed = swallowed
+	s.remap = false
+	return orphaned
+}
+
+// refresh updates the layout tree which RestoreCommand is based on.
+func (s *Swallower) refresh() error {
+	tree, err := i3.GetTree()
+	if err != nil {
+		return err
+	}
+	s.tree = tree
+	return nil
+}
+
+// run runs command, ignoring unsuccessful results, e.g. because the terminal
+// window was closed in the meantime.
+func run(command string) error {
+	if _, err := i3.RunCommand(command); err != nil && !i3.IsUnsuccessful(err) {
+		return err
+	}
+	return nil
+}
+
+// Run swallows terminal windows in response to window events until i3 exits.
+//
+// Run is supported in i3 ≥ v4.14 (2017-09-04).
+func (s *Swallower) Run() error {
+	if err := s.refresh(); err != nil {
+		return err
+	}
+	return daemon.Run(s.Handle, i3.WindowEventType)
+}
+
+// ParentPID returns the parent process ID of the process with the specified
+// ID, as reported by /proc (i.e. on Linux).
+func ParentPID(pid int) (int, error) {
+	b, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return 0, err
+	}
+	return parseStat(b)
+}
+
+// parseStat returns the parent process ID from the contents of
+// /proc/<pid>/stat, which start with “pid (comm) state ppid”. As comm may
+// contain spaces and parentheses, the fields are located after the last ‘)’.
+func parseStat(b []byte) (int, error) {
+	idx := bytes.LastIndexByte(b, ')')
+	if idx == -1 {
+		return 0, fmt.Errorf("malformed stat: %q", b)
+	}
+	fields := bytes.Fields(b[idx+1:])
+	if len(fields) < 2 {
+		return 0, fmt.Errorf("malformed stat: %q", b)
+	}
+	return strconv.Atoi(string(fields[1]))
+}
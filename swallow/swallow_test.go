@@ -0,0 +1,227 @@
+package swallow
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.i3wm.org/i3/v4"
+)
+
+func window(id i3.NodeID, class string) *i3.Node {
+	return &i3.Node{
+		ID:               id,
+		Type:             i3.Con,
+		Window:           int64(id) * 100,
+		WindowProperties: i3.WindowProperties{Class: class},
+	}
+}
+
+func testTree() i3.Tree {
+	return i3.Tree{Root: &i3.Node{
+		ID:   1,
+		Type: i3.Root,
+		Nodes: []*i3.Node{{
+			ID:   2,
+			Type: i3.OutputNode,
+			Nodes: []*i3.Node{{
+				ID:   3,
+				Type: i3.Con,
+				Nodes: []*i3.Node{{
+					ID:   4,
+					Name: "1: dev",
+					Type: i3.WorkspaceNode,
+					Nodes: []*i3.Node{
+						window(10, "URxvt"),
+						window(11, "mpv"),
+						{
+							ID:   12,
+							Type: i3.Con,
+							Nodes: []*i3.Node{
+								window(13, "Emacs"),
+								window(14, "URxvt"),
+							},
+						},
+					},
+				}, {
+					ID:    5,
+					Name:  "2",
+					Type:  i3.WorkspaceNode,
+					Nodes: []*i3.Node{window(20, "mpv")},
+				}},
+			}},
+		}},
+	}}
+}
+
+func TestTerminal(t *testing.T) {
+	t.Parallel()
+
+	// Process hierarchy: 1 → 100 (terminal 10) → 101 (shell) → 110 (mpv)
+	//                    1 → 140 (terminal 14) → 130 (emacs 13) → 200 (mpv 20)
+	pids := map[int64]int{1000: 100, 1100: 110, 1300: 130, 1400: 140, 2000: 200}
+	parents := map[int]int{100: 1, 101: 100, 110: 101, 130: 140, 140: 1, 200: 130}
+	s := &Swallower{
+		windowPID: func(window int64) (int, error) {
+			if pid, ok := pids[window]; ok {
+				return pid, nil
+			}
+			return 0, fmt.Errorf("no _NET_WM_PID")
+		},
+		parentPID: func(pid int) (int, error) {
+			if ppid, ok := parents[pid]; ok {
+				return ppid, nil
+			}
+			return 0, os.ErrNotExist
+		},
+	}
+	tree := testTree()
+	for _, tt := range []struct {
+		terminals []string
+		w         i3.NodeID
+		want      i3.NodeID
+	}{
+		{w: 11, want: 10},
+		{w: 13, want: 14},
+		{w: 10, want: 0},
+		{w: 20, want: 14}, // Emacs is not in DefaultTerminals
+		{terminals: []string{"Emacs"}, w: 20, want: 13},
+		{terminals: []string{"URxvt"}, w: 11, want: 10},
+		{terminals: []string{"XTerm"}, w: 11, want: 0},
+	} {
+		s.Terminals = tt.terminals
+		var w *i3.Node
+		tree.Root.FindChild(func(n *i3.Node) bool {
+			if n.ID == tt.w {
+				w = n
+				return true
+			}
+			return false
+		})
+		var got i3.NodeID
+		if n := s.Terminal(tree, w); n != nil {
+			got = n.ID
+		}
+		if got != tt.want {
+			t.Errorf("Terminal(%d) (Terminals %q) = %d, want %d", tt.w, tt.terminals, got, tt.want)
+		}
+	}
+}
+
+func TestSwallows(t *testing.T) {
+	t.Parallel()
+
+	s := &Swallower{Deny: []string{"Gimp"}}
+	if !s.swallows("mpv") || s.swallows("Gimp") {
+		t.Errorf("swallows: Deny not respected")
+	}
+	s.Allow = []string{"mpv", "Gimp"}
+	if !s.swallows("mpv") || s.swallows("Gimp") || s.swallows("feh") {
+		t.Errorf("swallows: Allow not respected")
+	}
+}
+
+func TestRestoreCommand(t *testing.T) {
+	t.Parallel()
+
+	tree := testTree()
+	for _, tt := range []struct {
+		w    i3.NodeID
+		want string
+	}{
+		{
+			w:    11,
+			want: `[con_id=10] mark --add _swallow_99; [con_id=99] scratchpad show, floating disable, move container to mark _swallow_99; [con_id=10] unmark _swallow_99`,
+		},
+		{
+			w:    10,
+			want: `[con_id=11] mark --add _swallow_99; [con_id=99] scratchpad show, floating disable, move container to mark _swallow_99, swap container with con_id 11; [con_id=11] unmark _swallow_99`,
+		},
+		{
+			w:    13,
+			want: `[con_id=14] mark --add _swallow_99; [con_id=99] scratchpad show, floating disable, move container to mark _swallow_99, swap container with con_id 14; [con_id=14] unmark _swallow_99`,
+		},
+		{
+			// There is no neighboring window.
+			w:    20,
+			want: `[con_id=99] scratchpad show, floating disable, move container to workspace "2"`,
+		},
+		{
+			w:    50, // unknown
+			want: `[con_id=99] scratchpad show, floating disable`,
+		},
+	} {
+		if got := RestoreCommand(tree, tree, tt.w, 99); got != tt.want {
+			t.Errorf("RestoreCommand(%d):\ngot  %s\nwant %s", tt.w, got, tt.want)
+		}
+	}
+
+	// Since the tree was last fetched, window 10 closed and the workspace
+	// was renamed.
+	now := testTree()
+	ws := now.Root.Nodes[0].Nodes[0].Nodes[0]
+	ws.Name = "1: dev (2)"
+	ws.Nodes = ws.Nodes[1:]
+	want := `[con_id=99] scratchpad show, floating disable, move container to workspace "1: dev (2)"`
+	if got := RestoreCommand(tree, now, 11, 99); got != want {
+		t.Errorf("RestoreCommand(11) with stale tree:\ngot  %s\nwant %s", got, want)
+	}
+}
+
+func TestParseStat(t *testing.T) {
+	t.Parallel()
+
+	got, err := parseStat([]byte("4242 (a (weird) name) S 4200 4242 4242 0 -1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := 4200; got != want {
+		t.Errorf("parseStat: got %d, want %d", got, want)
+	}
+	if _, err := parseStat([]byte("4242 (truncated")); err == nil {
+		t.Errorf("parseStat: unexpectedly succeeded on malformed input")
+	}
+}
+
+func TestParentPID(t *testing.T) {
+	t.Parallel()
+
+	if _, err := os.Stat("/proc/self/stat"); err != nil {
+		t.Skip("/proc not available")
+	}
+	got, err := ParentPID(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := os.Getppid(); got != want {
+		t.Errorf("ParentPID: got %d, want %d", got, want)
+	}
+}
+
+func TestRemapIDs(t *testing.T) {
+	t.Parallel()
+
+	// Container IDs from before an i3 restart.
+	s := &Swallower{
+		remap: true,
+		swallowed: map[i3.NodeID]swallowing{
+			91: {terminal: 90, window: 1100, terminalWindow: 1000},
+			93: {terminal: 94, window: 9300, terminalWindow: 1400}, // window closed
+			95: {terminal: 96, window: 9500, terminalWindow: 9600}, // both closed
+		},
+	}
+	orphaned := s.remapIDs(testTree())
+	want := map[i3.NodeID]swallowing{
+		11: {terminal: 10, window: 1100, terminalWindow: 1000},
+	}
+	if diff := cmp.Diff(want, s.swallowed, cmp.AllowUnexported(swallowing{})); diff != "" {
+		t.Errorf("remapIDs: unexpected swallowed: (-want +got)\n%s", diff)
+	}
+	if diff := cmp.Diff([]i3.NodeID{14}, orphaned); diff != "" {
+		t.Errorf("remapIDs: unexpected orphaned terminals: (-want +got)\n%s", diff)
+	}
+	if s.remap {
+		t.Errorf("remapIDs did not reset remap")
+	}
+}
@@ -1,8 +1,6 @@
 package i3
 
-import (
-	"encoding/json"
-)
+import "encoding/json"
 
 // NodeType indicates the specific kind of Node.
 type NodeType string
@@ -115,6 +113,10 @@ type Node struct {
 	ScratchpadState    string           `json:"scratchpad_state"`
 	AppID              string           `json:"app_id"` // if talking to Sway: Wayland App ID
 	Sticky             bool             `json:"sticky"`
+
+	// Raw is the node’s JSON object as sent by i3 (including its children),
+	// e.g. for fields which Node does not model. Only GetTreeRaw sets Raw.
+	Raw json.RawMessage `json:"-"`
 }
 
 // FindChild returns the first Node matching predicate, using pre-order
@@ -0,0 +1,254 @@
+package i3
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ChangeKind indicates what changed about a node between two layout trees.
+type ChangeKind int
+
+// DiffTrees reports the following kinds of changes:
+const (
+	NodeAdded         ChangeKind = iota // node only present in the newer tree
+	NodeRemoved                         // node only present in the older tree
+	NodeMoved                           // different parent, or different position among siblings
+	LayoutChanged                       // Layout differs
+	RectChanged                         // Rect differs
+	FocusChanged                        // Focused differs
+	MarksChanged                        // Marks differ (ignoring order)
+	FloatingChanged                     // IsFloating differs
+	FullscreenChanged                   // FullscreenMode differs
+)
+
+var changeKindNames = [...]string{
+	NodeAdded:         "added",
+	NodeRemoved:       "removed",
+	NodeMoved:         "moved",
+	LayoutChanged:     "layout",
+	RectChanged:       "rect",
+	FocusChanged:      "focus",
+	MarksChanged:      "marks",
+	FloatingChanged:   "floating",
+	FullscreenChanged: "fullscreen",
+}
+
+// String implements fmt.Stringer.
+func (k ChangeKind) String() string {
+	if k < 0 || int(k) >= len(changeKindNames) {
+		return "ChangeKind(" + strconv.Itoa(int(k)) + ")"
+	}
+	return changeKindNames[k]
+}
+
+// Change describes a single difference between two layout trees.
+type Change struct {
+	Kind ChangeKind
+	ID   NodeID
+
+	// Old and New are the node in the older and newer tree, respectively.
+	// Old is nil for NodeAdded, New is nil for NodeRemoved.
+	Old, New *Node
+
+	// OldParent and NewParent are the IDs of the node’s parent in the older
+	// and newer tree, respectively, or 0 if the node is absent (or the root).
+	OldParent, NewParent NodeID
+}
+
+// String returns a human-readable description of c.
+func (c Change) String() string {
+	switch c.Kind {
+	case NodeAdded:
+		return fmt.Sprintf("added %s to %d", c.New.label(), c.NewParent)
+	case NodeRemoved:
+		return fmt.Sprintf("removed %s from %d", c.Old.label(), c.OldParent)
+	case NodeMoved:
+		if c.OldParent == c.NewParent {
+			return fmt.Sprintf("moved %s within %d", c.New.label(), c.NewParent)
+		}
+		return fmt.Sprintf("moved %s from %d to %d", c.New.label(), c.OldParent, c.NewParent)
+	case LayoutChanged:
+		return fmt.Sprintf("%s: layout %s → %s", c.New.label(), c.Old.Layout, c.New.Layout)
+	case RectChanged:
+		return fmt.Sprintf("%s: rect %s → %s", c.New.label(), c.Old.Rect, c.New.Rect)
+	case FocusChanged:
+		if c.New.Focused {
+			return fmt.Sprintf("%s: focused", c.New.label())
+		}
+		return fmt.Sprintf("%s: unfocused", c.New.label())
+	case MarksChanged:
+		return fmt.Sprintf("%s: marks %q → %q", c.New.label(), c.Old.Marks, c.New.Marks)
+	case FloatingChanged:
+		return fmt.Sprintf("%s: floating %s → %s", c.New.label(), c.Old.Floating, c.New.Floating)
+	case FullscreenChanged:
+		return fmt.Sprintf("%s: fullscreen mode %d → %d", c.New.label(), c.Old.FullscreenMode, c.New.FullscreenMode)
+	}
+	return fmt.Sprintf("%v change of node %d", c.Kind, c.ID)
+}
+
+// String returns r formatted as “x,y width×height”.
+func (r Rect) String() string {
+	return fmt.Sprintf("%d,%d %d×%d", r.X, r.Y, r.Width, r.Height)
+}
+
+// label returns a short description of n for use in messages.
+func (n *Node) label() string {
+	if n.Name == "" {
+		return fmt.Sprintf("%s %d", n.Type, n.ID)
+	}
+	return fmt.Sprintf("%s %d %q", n.Type, n.ID, n.Name)
+}
+
+// treeIndex maps node IDs to their location in a layout tree.
+type treeIndex map[NodeID]treeEntry
+
+type treeEntry struct {
+	node   *Node
+	parent *Node
+}
+
+func indexTree(root *Node) (treeIndex, []*Node) {
+	idx := make(treeIndex)
+	var order []*Node // pre-order
+	var walk func(n, parent *Node)
+	walk = func(n, parent *Node) {
+		idx[n.ID] = treeEntry{node: n, parent: parent}
+		order = append(order, n)
+		for _, c := range n.Nodes {
+			walk(c, n)
+		}
+		for _, c := range n.FloatingNodes {
+			walk(c, n)
+		}
+	}
+	if root != nil {
+		walk(root, nil)
+	}
+	return idx, order
+}
+
+func (e treeEntry) parentID() NodeID {
+	if e.parent == nil {
+		return 0
+	}
+	return e.parent.ID
+}
+
+// DiffTrees compares two snapshots of the layout tree, matching nodes by
+// their ID, and returns the changes between them: first all removed nodes in
+// pre-order of the older tree, then all other changes in pre-order of the
+// newer tree.
+func DiffTrees(before, after Tree) []Change {
+	oldIdx, oldOrder := indexTree(before.Root)
+	newIdx, newOrder := indexTree(after.Root)
+
+	var changes []Change
+	for _, o := range oldOrder {
+		if _, ok := newIdx[o.ID]; ok {
+			continue
+		}
+		changes = append(changes, Change{
+			Kind:      NodeRemoved,
+			ID:        o.ID,
+			Old:       o,
+			OldParent: oldIdx[o.ID].parentID(),
+		})
+	}
+
+	moved := reorderedChildren(oldIdx, newOrder)
+	for _, n := range newOrder {
+		ne := newIdx[n.ID]
+		oe, ok := oldIdx[n.ID]
+		change := Change{
+			ID:        n.ID,
+			Old:       oe.node,
+			New:       n,
+			OldParent: oe.parentID(),
+			NewParent: ne.parentID(),
+		}
+		add := func(kind ChangeKind) {
+			change.Kind = kind
+			changes = append(changes, change)
+		}
+		if !ok {
+			add(NodeAdded)
+			continue
+		}
+		o := oe.node
+		if change.OldParent != change.NewParent || moved[n.ID] {
+			add(NodeMoved)
+		}
+		if o.Layout != n.Layout {
+			add(LayoutChanged)
+		}
+		if o.Rect != n.Rect {
+			add(RectChanged)
+		}
+		if o.Focused != n.Focused {
+			add(FocusChanged)
+		}
+		if !sameMarks(o.Marks, n.Marks) {
+			add(MarksChanged)
+		}
+		if o.IsFloating() != n.IsFloating() {
+			add(FloatingChanged)
+		}
+		if o.FullscreenMode != n.FullscreenMode {
+			add(FullscreenChanged)
+		}
+	}
+	return changes
+}
+
+// reorderedChildren returns the IDs of nodes which kept their parent, but
+// whose position relative to their siblings (which are present in both trees)
+// changed.
+func reorderedChildren(oldIdx treeIndex, newOrder []*Node) map[NodeID]bool {
+	moved := make(map[NodeID]bool)
+	// common returns the IDs of children which are also in other, in order.
+	common := func(children, other []*Node) []NodeID {
+		in := make(map[NodeID]bool, len(other))
+		for _, c := range other {
+			in[c.ID] = true
+		}
+		var ids []NodeID
+		for _, c := range children {
+			if in[c.ID] {
+				ids = append(ids, c.ID)
+			}
+		}
+		return ids
+	}
+	for _, n := range newOrder {
+		oe, ok := oldIdx[n.ID]
+		if !ok {
+			continue
+		}
+		for _, lists := range [][2][]*Node{
+			{oe.node.Nodes, n.Nodes},
+			{oe.node.FloatingNodes, n.FloatingNodes},
+		} {
+			before := common(lists[0], lists[1])
+			after := common(lists[1], lists[0])
+			for i := range after {
+				if before[i] != after[i] {
+					moved[after[i]] = true
+				}
+			}
+		}
+	}
+	return moved
+}
+
+func sameMarks(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a = append([]string(nil), a...)
+	b = append([]string(nil), b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	return strings.Join(a, "\x00") == strings.Join(b, "\x00")
+}
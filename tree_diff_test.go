@@ -0,0 +1,63 @@
+package i3
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDiffTrees(t *testing.T) {
+	t.Parallel()
+
+	before := Tree{Root: testTree()}
+	if got := DiffTrees(before, Tree{Root: testTree()}); len(got) > 0 {
+		t.Fatalf("DiffTrees of identical trees unexpectedly returned changes: %v", got)
+	}
+
+	root := testTree()
+	find := func(id NodeID) *Node {
+		return root.FindChild(func(n *Node) bool { return n.ID == id })
+	}
+	ws1, ws2 := find(20), find(30)
+	xterm, firefox, emacs, split := find(21), find(22), find(31), find(32)
+	// swap XTerm and Firefox, move focus to XTerm, mark and resize it
+	ws1.Nodes = []*Node{firefox, xterm}
+	firefox.Focused = false
+	xterm.Focused = true
+	xterm.Marks = []string{"a", "term"}
+	xterm.Rect.X = 960
+	firefox.Rect.X = 0
+	// move Emacs to workspace 1 and make it fullscreen and floating
+	ws2.Nodes = []*Node{split}
+	ws1.FloatingNodes = []*Node{emacs}
+	emacs.Floating = UserOn
+	emacs.FullscreenMode = FullscreenOutput
+	// close mpv, make workspace 2 stacked
+	split.Nodes = nil
+	ws2.Layout = Stacked
+	// open a new window
+	ws2.Nodes = append(ws2.Nodes, &Node{ID: 34, Name: "new", Type: Con})
+
+	var got []string
+	for _, c := range DiffTrees(before, Tree{Root: root}) {
+		got = append(got, c.String())
+	}
+	want := []string{
+		`removed con 33 "mpv window" from 32`,
+		`moved con 22 "Firefox window" within 20`,
+		`con 22 "Firefox window": rect 960,0 960×1080 → 0,0 960×1080`,
+		`con 22 "Firefox window": unfocused`,
+		`moved con 21 "XTerm window" within 20`,
+		`con 21 "XTerm window": rect 0,0 960×1080 → 960,0 960×1080`,
+		`con 21 "XTerm window": focused`,
+		`con 21 "XTerm window": marks ["term"] → ["a" "term"]`,
+		`moved con 31 "Emacs window" from 30 to 20`,
+		`con 31 "Emacs window": floating auto_off → user_on`,
+		`con 31 "Emacs window": fullscreen mode 0 → 1`,
+		`workspace 30 "2": layout tabbed → stacked`,
+		`added con 34 "new" to 30`,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("unexpected changes: (-want +got)\n%s", diff)
+	}
+}
@@ -0,0 +1,158 @@
+package i3
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// RenderOptions control which parts of a layout tree Render and RenderDOT
+// include, and how.
+type RenderOptions struct {
+	// CollapseDockareas omits the children of dockarea nodes, i.e. dock
+	// clients such as i3bar.
+	CollapseDockareas bool
+
+	// HideScratch omits the __i3 output, which contains the scratchpad
+	// workspace __i3_scratch.
+	HideScratch bool
+
+	// ASCII uses ASCII instead of Unicode box-drawing characters.
+	ASCII bool
+}
+
+// Render writes the sub-tree rooted at n to w as an indented diagram, one
+// node per line, e.g.:
+//
+//	root 1 splith "root" [focus]
+//	└── output 10 output "HDMI-1" [focus]
+//	    └── con 12 splith "content" [focus]
+//	        └── workspace 20 splith "1: www" [focus]
+//	            ├── con 21 "XTerm" class=XTerm marks=term
+//	            └── con 22 "Firefox" class=Firefox [focused]
+//
+// Each line shows the node type, ID, layout (for containers with children
+// and workspaces), name, window class (or Wayland App ID), marks, floating,
+// fullscreen, sticky and urgency state, and whether the node is on the focus
+// path (see FindFocused) or focused.
+//
+// Render accepts a nil opts.
+func (n *Node) Render(w io.Writer, opts *RenderOptions) error {
+	if opts == nil {
+		opts = &RenderOptions{}
+	}
+	branch, last, vertical, space := "├── ", "└── ", "│   ", "    "
+	if opts.ASCII {
+		branch, last, vertical = "|-- ", "`-- ", "|   "
+	}
+	bw := bufio.NewWriter(w)
+	var render func(n *Node, onFocusPath bool, prefix, indent string)
+	render = func(n *Node, onFocusPath bool, prefix, indent string) {
+		bw.WriteString(prefix)
+		bw.WriteString(n.describe(onFocusPath))
+		bw.WriteByte('\n')
+		children := opts.children(n)
+		for i, c := range children {
+			childFocus := onFocusPath && len(n.Focus) > 0 && n.Focus[0] == c.ID
+			if i == len(children)-1 {
+				render(c, childFocus, indent+last, indent+space)
+			} else {
+				render(c, childFocus, indent+branch, indent+vertical)
+			}
+		}
+	}
+	render(n, true, "", "")
+	return bw.Flush()
+}
+
+// RenderDOT writes the sub-tree rooted at n to w as a Graphviz DOT digraph,
+// labeling nodes like Render does. Floating nodes are connected with dashed
+// edges, and nodes on the focus path are drawn bold.
+//
+// RenderDOT accepts a nil opts.
+func (n *Node) RenderDOT(w io.Writer, opts *RenderOptions) error {
+	if opts == nil {
+		opts = &RenderOptions{}
+	}
+	bw := bufio.NewWriter(w)
+	bw.WriteString("digraph tree {\n\tnode [shape=box];\n")
+	var render func(n *Node, onFocusPath bool)
+	render = func(n *Node, onFocusPath bool) {
+		attrs := "label=" + strconv.Quote(n.describe(false))
+		if onFocusPath {
+			attrs += ", style=bold"
+		}
+		fmt.Fprintf(bw, "\tn%d [%s];\n", n.ID, attrs)
+		for _, c := range opts.children(n) {
+			style := ""
+			if c.Type == FloatingCon {
+				style = " [style=dashed]"
+			}
+			fmt.Fprintf(bw, "\tn%d -> n%d%s;\n", n.ID, c.ID, style)
+			render(c, onFocusPath && len(n.Focus) > 0 && n.Focus[0] == c.ID)
+		}
+	}
+	render(n, true)
+	bw.WriteString("}\n")
+	return bw.Flush()
+}
+
+// children returns the tiling and floating children of n which opts include.
+func (opts *RenderOptions) children(n *Node) []*Node {
+	if opts.CollapseDockareas && n.Type == DockareaNode {
+		return nil
+	}
+	children := make([]*Node, 0, len(n.Nodes)+len(n.FloatingNodes))
+	for _, c := range n.Nodes {
+		if opts.HideScratch && c.Type == OutputNode && c.Name == "__i3" {
+			continue
+		}
+		children = append(children, c)
+	}
+	return append(children, n.FloatingNodes...)
+}
+
+// describe returns a one-line description of n, see Render.
+func (n *Node) describe(onFocusPath bool) string {
+	parts := []string{string(n.Type), strconv.FormatInt(int64(n.ID), 10)}
+	if len(n.Nodes) > 0 || n.Type == WorkspaceNode || n.Type == Root {
+		parts = append(parts, string(n.Layout))
+	}
+	if n.Name != "" {
+		parts = append(parts, strconv.Quote(n.Name))
+	}
+	if n.WindowProperties.Class != "" {
+		parts = append(parts, "class="+n.WindowProperties.Class)
+	} else if n.AppID != "" {
+		parts = append(parts, "app_id="+n.AppID)
+	}
+	if len(n.Marks) > 0 {
+		parts = append(parts, "marks="+strings.Join(n.Marks, ","))
+	}
+	if n.Type != FloatingCon && n.IsFloating() {
+		parts = append(parts, "floating")
+	}
+	if n.Type != WorkspaceNode {
+		switch n.FullscreenMode {
+		case FullscreenOutput:
+			parts = append(parts, "fullscreen")
+		case FullscreenGlobal:
+			parts = append(parts, "fullscreen=global")
+		}
+	}
+	if n.Sticky {
+		parts = append(parts, "sticky")
+	}
+	if n.Urgent {
+		parts = append(parts, "urgent")
+	}
+	switch {
+	case n.Focused:
+		parts = append(parts, "[focused]")
+	case onFocusPath:
+		parts = append(parts, "[focus]")
+	}
+	return strings.Join(parts, " ")
+}
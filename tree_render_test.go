@@ -0,0 +1,69 @@
+package i3
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRender(t *testing.T) {
+	t.Parallel()
+
+	var buf strings.Builder
+	if err := testTree().Render(&buf, &RenderOptions{HideScratch: true}); err != nil {
+		t.Fatal(err)
+	}
+	want := `root 1 splith "root" [focus]
+└── output 10 output "HDMI-1" [focus]
+    ├── dockarea 11 "topdock"
+    ├── con 12 splith "content" [focus]
+    │   ├── workspace 20 splith "1: www" [focus]
+    │   │   ├── con 21 "XTerm window" class=XTerm marks=term
+    │   │   └── con 22 "Firefox window" class=Firefox [focused]
+    │   └── workspace 30 tabbed "2" urgent
+    │       ├── con 31 "Emacs window" class=Emacs
+    │       └── con 32 splitv
+    │           └── con 33 "mpv window" class=mpv urgent
+    └── dockarea 13 "bottomdock"
+`
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Fatalf("unexpected Render output: (-want +got)\n%s", diff)
+	}
+
+	buf.Reset()
+	scratch := testTree().Nodes[0]
+	if err := scratch.Render(&buf, &RenderOptions{ASCII: true}); err != nil {
+		t.Fatal(err)
+	}
+	want = "output 2 output \"__i3\" [focus]\n" +
+		"`-- con 3 splith \"content\" [focus]\n" +
+		"    `-- workspace 4 splith \"__i3_scratch\" [focus]\n" +
+		"        `-- floating_con 5 splith [focus]\n" +
+		"            `-- con 6 \"Scratch window\" class=Scratch floating [focus]\n"
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Fatalf("unexpected Render output: (-want +got)\n%s", diff)
+	}
+}
+
+func TestRenderDOT(t *testing.T) {
+	t.Parallel()
+
+	var buf strings.Builder
+	ws := testTree().Nodes[0].Nodes[0].Nodes[0]
+	if err := ws.RenderDOT(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	want := `digraph tree {
+	node [shape=box];
+	n4 [label="workspace 4 splith \"__i3_scratch\"", style=bold];
+	n4 -> n5 [style=dashed];
+	n5 [label="floating_con 5 splith", style=bold];
+	n5 -> n6;
+	n6 [label="con 6 \"Scratch window\" class=Scratch floating", style=bold];
+}
+`
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Fatalf("unexpected RenderDOT output: (-want +got)\n%s", diff)
+	}
+}
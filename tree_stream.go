@@ -0,0 +1,200 @@
+package i3
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// GetTreeFunc is like GetTree, but calls visit for every node while decoding
+// the layout tree. If visit returns false, the children of the node are
+// skipped instead of decoded, e.g. to ignore dock areas or the __i3 output.
+//
+// visit is called before the children of a node are decoded, so the fields
+// which i3 sends after the children (Focus, FullscreenMode, Sticky and
+// Floating) are not yet set. visit may be nil.
+//
+// As GetTreeFunc decodes node by node, it takes more CPU time than GetTree,
+// but it never holds the JSON payload in memory as a whole, and skipped
+// sub-trees are read token by token without being buffered or decoded into
+// nodes. Prefer GetTree unless the layout tree is large or most of it can be
+// skipped.
+//
+// GetTreeFunc is supported in i3 ≥ v4.0 (2011-07-31).
+func GetTreeFunc(visit func(n *Node) bool) (Tree, error) {
+	var root *Node
+	err := roundTripReader(messageTypeGetTree, nil, func(r io.Reader) error {
+		root = &Node{} // in case of a retry
+		return decodeTree(r, root, visit)
+	})
+	if err != nil {
+		return Tree{}, err
+	}
+	return Tree{Root: root}, nil
+}
+
+// GetTreeRaw is like GetTree, but additionally sets the Raw field of all
+// nodes. As the raw JSON is retained, GetTreeRaw needs more memory than
+// GetTree.
+//
+// GetTreeRaw is supported in i3 ≥ v4.0 (2011-07-31).
+func GetTreeRaw() (Tree, error) {
+	reply, err := roundTrip(messageTypeGetTree, nil)
+	if err != nil {
+		return Tree{}, err
+	}
+	root := &Node{}
+	d := &treeDecoder{
+		dec: json.NewDecoder(bytes.NewReader(reply.Payload)),
+		raw: reply.Payload,
+	}
+	if err := d.node(root); err != nil {
+		return Tree{}, err
+	}
+	return Tree{Root: root}, nil
+}
+
+// nodeFields maps JSON names to the indices of the corresponding Node fields.
+var nodeFields = func() map[string][]int {
+	fields := make(map[string][]int)
+	t := reflect.TypeOf(Node{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" && name != "nodes" && name != "floating_nodes" {
+			fields[name] = []int{i}
+		}
+	}
+	return fields
+}()
+
+// decodeTree decodes the layout tree from r node by node, so that the JSON
+// payload is never held in memory as a whole.
+func decodeTree(r io.Reader, root *Node, visit func(n *Node) bool) error {
+	d := &treeDecoder{dec: json.NewDecoder(r), visit: visit}
+	return d.node(root)
+}
+
+type treeDecoder struct {
+	dec   *json.Decoder
+	visit func(n *Node) bool
+	raw   []byte // if non-nil, the input, for setting Node.Raw
+}
+
+func (d *treeDecoder) node(n *Node) error {
+	dec := d.dec
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+	start := dec.InputOffset() - 1 // offset of {
+	visited, descend := false, true
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, _ := tok.(string)
+		switch key {
+		case "nodes", "floating_nodes":
+			if !visited {
+				visited = true
+				descend = d.visit == nil || d.visit(n)
+			}
+			if !descend {
+				if err := skipValue(dec); err != nil {
+					return err
+				}
+				continue
+			}
+			children, err := d.nodes()
+			if err != nil {
+				return err
+			}
+			if key == "nodes" {
+				n.Nodes = children
+			} else {
+				n.FloatingNodes = children
+			}
+
+		default:
+			idx, ok := nodeFields[key]
+			if !ok {
+				if err := skipValue(dec); err != nil {
+					return err
+				}
+				continue
+			}
+			field := reflect.ValueOf(n).Elem().FieldByIndex(idx).Addr().Interface()
+			if err := dec.Decode(field); err != nil {
+				return fmt.Errorf("decoding %q: %v", key, err)
+			}
+		}
+	}
+	if !visited && d.visit != nil {
+		d.visit(n)
+	}
+	if err := expectDelim(dec, '}'); err != nil {
+		return err
+	}
+	if d.raw != nil {
+		n.Raw = d.raw[start:dec.InputOffset()]
+	}
+	return nil
+}
+
+func (d *treeDecoder) nodes() ([]*Node, error) {
+	dec := d.dec
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if tok == nil {
+		return nil, nil // null
+	}
+	if d, ok := tok.(json.Delim); !ok || d != '[' {
+		return nil, fmt.Errorf("unexpected %v, expected [", tok)
+	}
+	nodes := []*Node{}
+	for dec.More() {
+		n := &Node{}
+		if err := d.node(n); err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes, expectDelim(dec, ']')
+}
+
+// skipValue reads the next JSON value token by token, so that large values
+// (e.g. skipped sub-trees) are not buffered as a whole.
+func skipValue(dec *json.Decoder) error {
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := tok.(json.Delim); !ok || d != want {
+		return fmt.Errorf("unexpected %v, expected %v", tok, want)
+	}
+	return nil
+}
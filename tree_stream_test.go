@@ -0,0 +1,267 @@
+package i3
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestDecodeTree(t *testing.T) {
+	t.Parallel()
+
+	b, err := json.Marshal(testTree())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want Node
+	if err := json.Unmarshal(b, &want); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("All", func(t *testing.T) {
+		var got Node
+		if err := decodeTree(bytes.NewReader(b), &got, nil); err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(&want, &got); diff != "" {
+			t.Errorf("decodeTree: unexpected result: (-want +got)\n%s", diff)
+		}
+	})
+
+	t.Run("Prune", func(t *testing.T) {
+		var visited []NodeID
+		var got Node
+		err := decodeTree(bytes.NewReader(b), &got, func(n *Node) bool {
+			visited = append(visited, n.ID)
+			return n.Name != "__i3" && n.Type != DockareaNode
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff([]NodeID{1, 2, 10, 11, 12, 20, 21, 22, 30, 31, 32, 33, 13}, visited); diff != "" {
+			t.Errorf("visited nodes: (-want +got)\n%s", diff)
+		}
+		if n := got.Nodes[0]; n.Name != "__i3" || len(n.Nodes) != 0 {
+			t.Errorf("__i3 output unexpectedly not pruned: %+v", n)
+		}
+		if got, want := nodeIDs(got.FindChild(func(n *Node) bool { return n.ID == 12 }).Nodes), []NodeID{20, 30}; !cmp.Equal(got, want) {
+			t.Errorf("content: got children %v, want %v", got, want)
+		}
+	})
+
+	t.Run("Raw", func(t *testing.T) {
+		var got Node
+		d := &treeDecoder{dec: json.NewDecoder(bytes.NewReader(b)), raw: b}
+		if err := d.node(&got); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got.Raw, b) {
+			t.Errorf("root: Raw is not the entire input")
+		}
+		got.FindChild(func(n *Node) bool {
+			var decoded Node
+			if err := json.Unmarshal(n.Raw, &decoded); err != nil {
+				t.Fatalf("node %d: %v", n.ID, err)
+			}
+			if diff := cmp.Diff(n, &decoded, cmpopts.IgnoreFields(Node{}, "Raw")); diff != "" {
+				t.Errorf("node %d: Raw does not match: (-want +got)\n%s", n.ID, diff)
+			}
+			return false // visit all nodes
+		})
+	})
+
+	t.Run("Ignored", func(t *testing.T) {
+		// Like json.Unmarshal, ignore fields tagged json:"-" and skip
+		// unknown values of any shape.
+		in := `{"id": 1, "-": "raw", "unknown": [{"a": [1, {"b": null}]}, "c"], "name": "root"}`
+		var got Node
+		if err := decodeTree(strings.NewReader(in), &got, nil); err != nil {
+			t.Fatal(err)
+		}
+		if got.ID != 1 || got.Name != "root" || got.Raw != nil {
+			t.Errorf("decodeTree(%s) = %+v", in, got)
+		}
+	})
+
+	t.Run("Malformed", func(t *testing.T) {
+		var got Node
+		if err := decodeTree(bytes.NewReader(b[:len(b)/2]), &got, nil); err == nil {
+			t.Errorf("decodeTree: unexpectedly succeeded on truncated input")
+		}
+	})
+}
+
+func TestRoundTripReaderSync(t *testing.T) {
+	t.Parallel()
+
+	order := binary.LittleEndian
+	var conn bytes.Buffer
+	conn.Write(msgBytes(order, messageTypeGetTree, `{"id": 1, "nodes": []}`))
+	conn.Write(msgBytes(order, messageTypeGetTree, `{"id": 2, "nodes": []}`))
+	sock := &socket{conn: &conn, order: order}
+	// fn reads only part of the first reply…
+	fnErr, err := sock.roundTripReader(messageTypeGetTree, nil, func(r io.Reader) error {
+		_, err := r.Read(make([]byte, 3))
+		return err
+	})
+	if fnErr != nil || err != nil {
+		t.Fatalf("roundTripReader: %v, %v", fnErr, err)
+	}
+	// …but the second reply is still received correctly.
+	var root Node
+	fnErr, err = sock.roundTripReader(messageTypeGetTree, nil, func(r io.Reader) error {
+		return decodeTree(r, &root, nil)
+	})
+	if fnErr != nil || err != nil {
+		t.Fatalf("roundTripReader: %v, %v", fnErr, err)
+	}
+	if got, want := root.ID, NodeID(2); got != want {
+		t.Errorf("unexpected reply: got node %d, want node %d", got, want)
+	}
+}
+
+// benchmarkTree returns the JSON encoding of a layout tree with the specified
+// number of outputs, workspaces per output and windows per workspace.
+func benchmarkTree(outputs, workspaces, windows int) []byte {
+	id := NodeID(0)
+	node := func(name string, typ NodeType, children ...*Node) *Node {
+		id++
+		return &Node{
+			ID:     id,
+			Name:   name,
+			Type:   typ,
+			Border: NormalBorder,
+			Layout: SplitH,
+			Rect:   Rect{Width: 1920, Height: 1080},
+			Marks:  []string{},
+			Focus:  []NodeID{},
+			Nodes:  children,
+		}
+	}
+	root := node("root", Root)
+	for o := 0; o < outputs; o++ {
+		content := node("content", Con)
+		for w := 0; w < workspaces; w++ {
+			ws := node(fmt.Sprint(o*workspaces+w+1), WorkspaceNode)
+			for i := 0; i < windows; i++ {
+				win := node(fmt.Sprintf("window %d", i), Con)
+				win.Window = int64(win.ID)
+				win.WindowProperties = WindowProperties{
+					Title:    win.Name,
+					Class:    "URxvt",
+					Instance: "urxvt",
+				}
+				ws.Nodes = append(ws.Nodes, win)
+			}
+			content.Nodes = append(content.Nodes, ws)
+		}
+		root.Nodes = append(root.Nodes, node(fmt.Sprintf("DP-%d", o), OutputNode,
+			node("topdock", DockareaNode), content, node("bottomdock", DockareaNode)))
+	}
+	b, err := json.Marshal(root)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// heapSampler is an io.Reader which samples the live heap (after a garbage
+// collection) whenever it is read from or sample is called, and records by how
+// much it grew at most.
+type heapSampler struct {
+	r    io.Reader
+	base uint64
+	peak uint64
+}
+
+func liveHeap() uint64 {
+	// Collect twice, as the first collection only moves sync.Pool contents
+	// (e.g. of encoding/json) to a victim cache.
+	runtime.GC()
+	runtime.GC()
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	return ms.HeapAlloc
+}
+
+func (s *heapSampler) sample() {
+	if l := liveHeap(); l > s.base && l-s.base > s.peak {
+		s.peak = l - s.base
+	}
+}
+
+func (s *heapSampler) Read(p []byte) (int, error) {
+	s.sample()
+	return s.r.Read(p)
+}
+
+// benchmarkDecode benchmarks decoding the layout tree from an io.Reader (in
+// place of i3’s socket) of the specified length. decode calls sample (if
+// non-nil) where its live heap peaks between reads. The peak growth of the live
+// heap while decoding once is reported as metric peak-heap-B.
+func benchmarkDecode(b *testing.B, decode func(r io.Reader, length int, sample func()) (*Node, error)) {
+	payload := benchmarkTree(4, 10, 20)
+	hs := &heapSampler{r: bytes.NewReader(payload), base: liveHeap()}
+	root, err := decode(hs, len(payload), hs.sample)
+	if err != nil {
+		b.Fatal(err)
+	}
+	hs.sample()
+	runtime.KeepAlive(root)
+
+	b.SetBytes(int64(len(payload)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := decode(bytes.NewReader(payload), len(payload), nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(hs.peak), "peak-heap-B")
+}
+
+// BenchmarkTreeUnmarshal measures GetTree, which reads the entire payload into
+// memory before unmarshaling it.
+func BenchmarkTreeUnmarshal(b *testing.B) {
+	benchmarkDecode(b, func(r io.Reader, length int, sample func()) (*Node, error) {
+		// Like recvMsg, allocate exactly the message length.
+		buf := make([]byte, length)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		var root Node
+		if err := json.Unmarshal(buf, &root); err != nil {
+			return nil, err
+		}
+		if sample != nil {
+			sample() // buf and root are both live
+		}
+		runtime.KeepAlive(buf)
+		return &root, nil
+	})
+}
+
+func BenchmarkTreeDecode(b *testing.B) {
+	benchmarkDecode(b, func(r io.Reader, _ int, _ func()) (*Node, error) {
+		var root Node
+		return &root, decodeTree(r, &root, nil)
+	})
+}
+
+func BenchmarkTreeDecodePrune(b *testing.B) {
+	benchmarkDecode(b, func(r io.Reader, _ int, _ func()) (*Node, error) {
+		var root Node
+		return &root, decodeTree(r, &root, func(n *Node) bool {
+			// Only decode the first output.
+			return n.Type != OutputNode || n.Name == "DP-0"
+		})
+	})
+}
@@ -25,3 +25,19 @@ func (child *Node) FindParent() *Node {
 func (n *Node) IsFloating() bool {
 	return strings.HasSuffix(string(n.Floating), "_on")
 }
+
+// PathTo returns the nodes from n to the node with the specified id
+// (inclusive), or nil if the sub-tree of n contains no such node.
+func (n *Node) PathTo(id NodeID) []*Node {
+	if n.ID == id {
+		return []*Node{n}
+	}
+	for _, children := range [][]*Node{n.Nodes, n.FloatingNodes} {
+		for _, c := range children {
+			if path := c.PathTo(id); path != nil {
+				return append([]*Node{n}, path...)
+			}
+		}
+	}
+	return nil
+}
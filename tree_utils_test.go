@@ -5,6 +5,8 @@ import (
 	"os"
 	"os/exec"
 	"testing"
+
+	"github.com/google/go-cmp/cmp"
 )
 
 // TestTreeUtilsSubprocess runs in a process which has been started with
@@ -116,3 +118,23 @@ func TestTreeUtils(t *testing.T) {
 		t.Fatal(err.Error())
 	}
 }
+
+func TestPathTo(t *testing.T) {
+	t.Parallel()
+
+	root := testTree()
+	for _, tt := range []struct {
+		id   NodeID
+		want []NodeID
+	}{
+		{1, []NodeID{1}},
+		{33, []NodeID{1, 10, 12, 30, 32, 33}},
+		{6, []NodeID{1, 2, 3, 4, 5, 6}}, // via floating nodes
+		{99, []NodeID{}},
+	} {
+		got := nodeIDs(root.PathTo(tt.id))
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("PathTo(%d): unexpected path: (-want +got)\n%s", tt.id, diff)
+		}
+	}
+}
@@ -3,7 +3,7 @@ package i3
 import (
 	"encoding/json"
 	"fmt"
-	"log"
+	"sync"
 )
 
 // Version describes an i3 version.
@@ -26,46 +26,155 @@ func GetVersion() (Version, error) {
 	if err != nil {
 		return Version{}, err
 	}
+	return parseVersion(reply.Payload)
+}
+
+// GetVersionRaw is like GetVersion, but additionally returns the JSON object
+// as sent by i3, e.g. for fields which Version does not model.
+//
+// GetVersionRaw is supported in i3 ≥ v4.3 (2012-09-19).
+func GetVersionRaw() (Version, json.RawMessage, error) {
+	reply, err := roundTrip(messageTypeGetVersion, nil)
+	if err != nil {
+		return Version{}, nil, err
+	}
+	v, err := parseVersion(reply.Payload)
+	return v, reply.Payload, err
+}
 
+// parseVersion parses a GET_VERSION reply and updates the cached version.
+func parseVersion(payload []byte) (Version, error) {
 	var v Version
-	err = json.Unmarshal(reply.Payload, &v)
-	return v, err
+	if err := json.Unmarshal(payload, &v); err != nil {
+		return v, err
+	}
+	setVersion(v)
+	return v, nil
+}
+
+// version is a lazily-initialized copy of i3’s GET_VERSION reply, which is
+// re-queried whenever a connection to i3 is re-established: i3 might have
+// been upgraded in-place and restarted, or SocketPathHook might now return the
+// socket of a different i3.
+var version struct {
+	sync.Mutex
+	v Version // zero until known
+}
+
+// VersionChangedHook, if non-nil, is called when the version of i3 differs
+// after re-connecting, e.g. after an in-place upgrade and Restart. Long-running
+// programs can use it to re-check which features are available, see
+// Capabilities.
+//
+// VersionChangedHook is called in a separate goroutine (so that it can send
+// requests to i3), one call after the other in the order of the changes.
+var VersionChangedHook func(old, new Version)
+
+// versionChanges queues the calls of VersionChangedHook, which a single
+// goroutine makes while the queue is not empty.
+var versionChanges struct {
+	sync.Mutex
+	queue   []func()
+	running bool
 }
 
-// version is a lazily-initialized, possibly stale copy of i3’s GET_VERSION
-// reply. Access only values which don’t change, e.g. Major, Minor.
-var version Version
+// setVersion updates the cached version and calls VersionChangedHook if a
+// previously known version changed.
+func setVersion(v Version) {
+	version.Lock()
+	old := version.v
+	version.v = v
+	// The loaded configuration file does not identify the version.
+	oldID, newID := old, v
+	oldID.LoadedConfigFileName, newID.LoadedConfigFileName = "", ""
+	changed := old.Major != 0 && oldID != newID
+	if hook := VersionChangedHook; changed && hook != nil {
+		// Queue while holding version’s lock, so that the calls are in the
+		// order of the changes.
+		queueVersionChange(func() { hook(old, v) })
+	}
+	version.Unlock()
+	if changed {
+		logger().Debug("i3 version changed", "old", old.HumanReadable, "new", v.HumanReadable)
+	}
+}
+
+func queueVersionChange(fn func()) {
+	versionChanges.Lock()
+	defer versionChanges.Unlock()
+	versionChanges.queue = append(versionChanges.queue, fn)
+	if versionChanges.running {
+		return
+	}
+	versionChanges.running = true
+	go func() {
+		for {
+			versionChanges.Lock()
+			if len(versionChanges.queue) == 0 {
+				versionChanges.running = false
+				versionChanges.Unlock()
+				return
+			}
+			fn := versionChanges.queue[0]
+			versionChanges.queue = versionChanges.queue[1:]
+			versionChanges.Unlock()
+			fn()
+		}
+	}()
+}
+
+// refreshVersion re-queries the version over a newly established connection,
+// unless the version was never queried.
+func refreshVersion(s *socket) error {
+	version.Lock()
+	known := version.v.Major != 0
+	version.Unlock()
+	if !known {
+		return nil // AtLeast will query the version when needed
+	}
+	reply, err := s.roundTrip(messageTypeGetVersion, nil)
+	if err != nil {
+		return err
+	}
+	_, err = parseVersion(reply.Payload)
+	return err
+}
 
 // versionWarning is used to only warn a single time when unsupported versions are
 // detected.
 var versionWarning bool
 
 // AtLeast returns nil if i3’s major version matches major and i3’s minor
-// version is at least minor or newer. Otherwise, it returns an error message
+// version is at least minor or newer. Otherwise, it returns a *VersionError
 // stating i3 is too old.
 func AtLeast(major int64, minor int64) error {
 	if major == 0 {
 		return fmt.Errorf("BUG: major == 0 is non-sensical. Is a lookup table entry missing?")
 	}
-	if version.Major == 0 {
+	version.Lock()
+	v := version.v
+	version.Unlock()
+	if v.Major == 0 {
 		var err error
-		version, err = GetVersion()
+		v, err = GetVersion()
 		if err != nil {
 			return err
 		}
 	}
 
-	if version.Variant != "" {
+	if v.Variant != "" {
 		if !versionWarning {
 			versionWarning = true
-			log.Printf("non standard i3 payload variant '%s' detected. Ignoring version check. This is fully unsupported.", version.Variant)
+			logger().Warn("non standard i3 payload variant detected. Ignoring version check. This is fully unsupported.", "variant", v.Variant)
 		}
 		return nil
 	}
 
-	if version.Major == major && version.Minor >= minor {
+	if v.Major == major && v.Minor >= minor {
 		return nil
 	}
 
-	return fmt.Errorf("i3 version too old: got %d.%d, want ≥ %d.%d", version.Major, version.Minor, major, minor)
+	err := &VersionError{Have: v, Want: Version{Major: major, Minor: minor}}
+	logger().Debug("i3 version mismatch", "have", v.HumanReadable, "error", err)
+	return err
 }
@@ -0,0 +1,121 @@
+package i3
+
+import (
+	"bytes"
+	"encoding/binary"
+	"log/slog"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRefreshVersion(t *testing.T) {
+	// Not parallel: modifies the cached version, VersionChangedHook and
+	// Logger.
+	var logs bytes.Buffer
+	Logger = slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	defer func() {
+		VersionChangedHook = nil
+		Logger = nil
+		setVersion(Version{})
+	}()
+
+	type change struct{ old, new Version }
+	changes := make(chan change, 1)
+	VersionChangedHook = func(old, new Version) {
+		changes <- change{old, new}
+	}
+
+	refresh := func(reply string) {
+		t.Helper()
+		order := binary.LittleEndian
+		client, server := net.Pipe()
+		defer client.Close()
+		go func() {
+			defer server.Close()
+			sock := &socket{conn: server, order: order}
+			msg, err := sock.recvMsg()
+			if err != nil {
+				return
+			}
+			server.Write(msgBytes(order, msg.Type, reply))
+		}()
+		if err := refreshVersion(&socket{conn: client, order: order}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Without a known version, refreshVersion does not send a request (which
+	// would block, as nobody replies).
+	if err := refreshVersion(&socket{}); err != nil {
+		t.Fatal(err)
+	}
+
+	setVersion(Version{Major: 4, Minor: 23, HumanReadable: "4.23"})
+	refresh(`{"major": 4, "minor": 23, "human_readable": "4.23", "loaded_config_file_name": "/etc/i3/config"}`)
+	refresh(`{"major": 4, "minor": 24, "human_readable": "4.24"}`)
+
+	select {
+	case c := <-changes:
+		if c.old.Minor != 23 || c.new.Minor != 24 {
+			t.Errorf("VersionChangedHook(%+v, %+v), want 4.23 → 4.24", c.old, c.new)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("VersionChangedHook not called")
+	}
+	select {
+	case c := <-changes:
+		t.Errorf("unexpected VersionChangedHook(%+v, %+v)", c.old, c.new)
+	default:
+	}
+
+	if err := AtLeast(4, 24); err != nil {
+		t.Errorf("AtLeast(4, 24) after refresh: %v", err)
+	}
+
+	if want := `msg="i3 version changed" old=4.23 new=4.24`; !strings.Contains(logs.String(), want) {
+		t.Errorf("log output %q does not contain %q", logs.String(), want)
+	}
+}
+
+func TestVersionChangedHookOrder(t *testing.T) {
+	// Not parallel: modifies the cached version and VersionChangedHook.
+	defer func() {
+		VersionChangedHook = nil
+		setVersion(Version{})
+	}()
+
+	var (
+		mu   sync.Mutex
+		got  []int64
+		done = make(chan struct{})
+	)
+	VersionChangedHook = func(old, new Version) {
+		time.Sleep(time.Millisecond) // let later changes queue up
+		mu.Lock()
+		defer mu.Unlock()
+		if old.Minor != new.Minor-1 {
+			t.Errorf("VersionChangedHook(%+v, %+v): not consecutive", old, new)
+		}
+		got = append(got, new.Minor)
+		if new.Minor == 24 {
+			close(done)
+		}
+	}
+	for minor := int64(20); minor <= 24; minor++ {
+		setVersion(Version{Major: 4, Minor: minor})
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("VersionChangedHook not called")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if want := []int64{21, 22, 23, 24}; !slices.Equal(got, want) {
+		t.Errorf("VersionChangedHook: got versions %v, want %v", got, want)
+	}
+}
@@ -33,3 +33,23 @@ func GetWorkspaces() ([]Workspace, error) {
 	err = json.Unmarshal(reply.Payload, &ws)
 	return ws, err
 }
+
+// GetWorkspacesRaw is like GetWorkspaces, but additionally returns the JSON
+// object of each workspace as sent by i3, e.g. for fields which Workspace
+// does not model.
+//
+// GetWorkspacesRaw is supported in i3 ≥ v4.0 (2011-07-31).
+func GetWorkspacesRaw() ([]Workspace, []json.RawMessage, error) {
+	reply, err := roundTrip(messageTypeGetWorkspaces, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var raw []json.RawMessage
+	if err := json.Unmarshal(reply.Payload, &raw); err != nil {
+		return nil, nil, err
+	}
+	var ws []Workspace
+	err = json.Unmarshal(reply.Payload, &ws)
+	return ws, raw, err
+}
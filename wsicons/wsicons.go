@@ -0,0 +1,157 @@
+// Package wsicons renames numbered workspaces to “<num>: <icons>”, where the
+// icons represent the windows on the workspace.
+package wsicons
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.i3wm.org/i3/v4"
+	"go.i3wm.org/i3/v4/internal/daemon"
+)
+
+// Renamer derives workspace names from the windows they contain.
+type Renamer struct {
+	// Icons maps window classes (or Wayland App IDs) to icons, e.g. Font
+	// Awesome glyphs. Keys are matched case-insensitively.
+	Icons map[string]string
+
+	// Default is the icon for windows whose class is not in Icons. If
+	// Default is empty, the lower-cased class is used.
+	Default string
+
+	// Separator separates icons, and defaults to a single space.
+	Separator string
+
+	// Dedupe shows each icon only once per workspace.
+	Dedupe bool
+}
+
+// icon returns the icon for window n.
+func (r *Renamer) icon(n *i3.Node) string {
+	class := n.WindowProperties.Class
+	if class == "" {
+		class = n.AppID
+	}
+	class = strings.ToLower(class)
+	for k, icon := range r.Icons {
+		if strings.ToLower(k) == class {
+			return icon
+		}
+	}
+	if r.Default != "" {
+		return r.Default
+	}
+	return class
+}
+
+// Name returns the name for workspace ws, or ws.Name for workspaces without a
+// number, which Name leaves alone.
+func (r *Renamer) Name(ws *i3.Node) string {
+	num, ok := workspaceNum(ws.Name)
+	if !ok {
+		return ws.Name
+	}
+	var (
+		icons []string
+		seen  = make(map[string]bool)
+	)
+	ws.FindChild(func(n *i3.Node) bool {
+		if n.Window == 0 && n.AppID == "" {
+			return false // not a window
+		}
+		icon := r.icon(n)
+		if icon == "" || (r.Dedupe && seen[icon]) {
+			return false
+		}
+		seen[icon] = true
+		icons = append(icons, icon)
+		return false // visit all nodes
+	})
+	if len(icons) == 0 {
+		return strconv.FormatInt(num, 10)
+	}
+	sep := r.Separator
+	if sep == "" {
+		sep = " "
+	}
+	return fmt.Sprintf("%d: %s", num, strings.Join(icons, sep))
+}
+
+// workspaceNum returns the number at the start of a workspace name, parsed
+// like i3 does: the longest prefix of digits, if followed by the end of the
+// name or a colon (or any other non-digit).
+func workspaceNum(name string) (int64, bool) {
+	end := 0
+	for end < len(name) && name[end] >= '0' && name[end] <= '9' {
+		end++
+	}
+	if end == 0 {
+		return 0, false
+	}
+	num, err := strconv.ParseInt(name[:end], 10, 64)
+	return num, err == nil
+}
+
+// Commands returns the rename commands which make the workspace names in
+// tree match Name.
+func (r *Renamer) Commands(tree i3.Tree) []string {
+	var cmds []string
+	tree.Root.FindChild(func(n *i3.Node) bool {
+		if n.Type != i3.WorkspaceNode || strings.HasPrefix(n.Name, "__") {
+			return false
+		}
+		if name := r.Name(n); name != n.Name {
+			cmds = append(cmds, fmt.Sprintf("rename workspace %s to %s", i3.Quote(n.Name), i3.Quote(name)))
+		}
+		return false // visit all nodes
+	})
+	return cmds
+}
+
+// Apply renames all workspaces of the current layout tree.
+func (r *Renamer) Apply() error {
+	tree, err := i3.GetTree()
+	if err != nil {
+		return err
+	}
+	for _, cmd := range r.Commands(tree) {
+		// Renames fail if the name is already taken, e.g. when two
+		// workspaces share a number. Keep going to rename the others.
+		if _, err := i3.RunCommand(cmd); err != nil && !i3.IsUnsuccessful(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run renames all workspaces, and again whenever windows are opened, closed
+// or moved, until i3 exits or Apply fails.
+//
+// Run is supported in i3 ≥ v4.14 (2017-09-04).
+func (r *Renamer) Run() error {
+	if err := r.Apply(); err != nil {
+		return err
+	}
+	return daemon.Run(func(ev i3.Event) error {
+		switch ev := ev.(type) {
+		case *i3.ShutdownEvent:
+			return nil
+
+		case *i3.WindowEvent:
+			switch ev.Change {
+			case "new", "close", "move", "floating":
+			default:
+				return nil
+			}
+
+		case *i3.WorkspaceEvent:
+			// Renames by other programs, or our own.
+			if ev.Change != "init" && ev.Change != "rename" && ev.Change != "move" {
+				return nil
+			}
+		}
+		return r.Apply()
+	}, i3.WindowEventType, i3.WorkspaceEventType)
+}
@@ -0,0 +1,74 @@
+package wsicons
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.i3wm.org/i3/v4"
+)
+
+func window(class string) *i3.Node {
+	return &i3.Node{
+		Type:             i3.Con,
+		Window:           1,
+		WindowProperties: i3.WindowProperties{Class: class},
+	}
+}
+
+func TestCommands(t *testing.T) {
+	t.Parallel()
+
+	tree := i3.Tree{Root: &i3.Node{
+		Type: i3.Root,
+		Nodes: []*i3.Node{{
+			Type: i3.OutputNode,
+			Nodes: []*i3.Node{{
+				Type: i3.Con,
+				Nodes: []*i3.Node{
+					{
+						Name: "1",
+						Type: i3.WorkspaceNode,
+						Nodes: []*i3.Node{
+							window("Firefox"),
+							{Type: i3.Con, Nodes: []*i3.Node{window("URxvt"), window("urxvt")}},
+						},
+						FloatingNodes: []*i3.Node{
+							{Type: i3.FloatingCon, Nodes: []*i3.Node{window("Gimp")}},
+						},
+					},
+					{
+						Name:  `2: "quoted" \ name`,
+						Type:  i3.WorkspaceNode,
+						Nodes: []*i3.Node{window("Emacs")},
+					},
+					{Name: "3: www", Type: i3.WorkspaceNode},
+					{Name: "4", Type: i3.WorkspaceNode},
+					{Name: "mail", Type: i3.WorkspaceNode, Nodes: []*i3.Node{window("Thunderbird")}},
+				},
+			}},
+		}},
+	}}
+	r := &Renamer{
+		Icons: map[string]string{
+			"firefox": "F",
+			"URXVT":   "T",
+		},
+		Dedupe: true,
+	}
+	got := r.Commands(tree)
+	want := []string{
+		`rename workspace "1" to "1: F T gimp"`,
+		`rename workspace "2: \"quoted\" \\ name" to "2: emacs"`,
+		`rename workspace "3: www" to "3"`,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("unexpected commands: (-want +got)\n%s", diff)
+	}
+
+	r.Dedupe = false
+	r.Default = "?"
+	r.Separator = "|"
+	if got, want := r.Name(tree.Root.Nodes[0].Nodes[0].Nodes[0]), "1: F|T|T|?"; got != want {
+		t.Fatalf("Name() = %q, want %q", got, want)
+	}
+}